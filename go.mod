@@ -5,6 +5,7 @@ go 1.16
 require (
 	github.com/chehsunliu/poker v0.0.0-20190908163705-e602358ef561
 	github.com/gofrs/uuid v4.1.0+incompatible
+	github.com/gorilla/websocket v1.5.0
 	github.com/opentracing/opentracing-go v1.2.0
 	github.com/prometheus/client_golang v1.11.0
 )
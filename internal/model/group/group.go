@@ -1 +1,239 @@
+// Package group models the groups of players that play at tables together,
+// who belongs to each group, what they are allowed to do and which tables the
+// group owns.
 package group
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	model "github.com/ekotlikoff/gopoker/internal/model/table"
+)
+
+const (
+	// Owner of a group, there is exactly one and they can do anything
+	Owner = Role("owner")
+	// Admin of a group, can manage players and the group's tables
+	Admin = Role("admin")
+	// Player member of a group, can play at the group's tables
+	Player = Role("player")
+)
+
+// ErrNotPermitted the member's role does not allow the change
+var ErrNotPermitted = errors.New("not permitted")
+
+type (
+	// Role a member's permissions within a Group
+	Role string
+
+	// Member of a Group, identified by their player name
+	Member struct {
+		Name string
+		Role Role
+	}
+
+	// Group players and the tables they play at
+	Group struct {
+		ID         string
+		Name       string
+		members    map[string]Role
+		tables     map[string]*model.Table
+		groupMutex sync.RWMutex
+	}
+
+	// Directory of every Group
+	Directory struct {
+		groups         map[string]*Group
+		nextID         int
+		directoryMutex sync.RWMutex
+	}
+)
+
+// rank orders roles by the permissions they grant
+func (role Role) rank() int {
+	switch role {
+	case Owner:
+		return 3
+	case Admin:
+		return 2
+	case Player:
+		return 1
+	}
+	return 0
+}
+
+// Valid if the role is one of Owner, Admin or Player
+func (role Role) Valid() bool {
+	return role.rank() > 0
+}
+
+// AtLeast if the role grants at least the permissions of other
+func (role Role) AtLeast(other Role) bool {
+	return role.rank() >= other.rank()
+}
+
+// NewDirectory create a directory with no groups
+func NewDirectory() *Directory {
+	return &Directory{groups: make(map[string]*Group), nextID: 1}
+}
+
+// Create a group named name owned by owner
+func (directory *Directory) Create(name string, owner string) (*Group,
+	error) {
+	if name == "" {
+		return nil, errors.New("create: missing group name")
+	} else if owner == "" {
+		return nil, errors.New("create: missing owner")
+	}
+	directory.directoryMutex.Lock()
+	defer directory.directoryMutex.Unlock()
+	group := &Group{
+		ID: strconv.Itoa(directory.nextID), Name: name,
+		members: map[string]Role{owner: Owner},
+		tables:  make(map[string]*model.Table),
+	}
+	directory.nextID++
+	directory.groups[group.ID] = group
+	return group, nil
+}
+
+// Get the group with id, nil if there is none
+func (directory *Directory) Get(id string) *Group {
+	directory.directoryMutex.RLock()
+	defer directory.directoryMutex.RUnlock()
+	return directory.groups[id]
+}
+
+// ForMember the groups name is a member of, ordered by ID
+func (directory *Directory) ForMember(name string) []*Group {
+	directory.directoryMutex.RLock()
+	defer directory.directoryMutex.RUnlock()
+	var groups []*Group
+	for _, group := range directory.groups {
+		if _, ok := group.Role(name); ok {
+			groups = append(groups, group)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		a, _ := strconv.Atoi(groups[i].ID)
+		b, _ := strconv.Atoi(groups[j].ID)
+		return a < b
+	})
+	return groups
+}
+
+// Role of the member named name, false if they are not a member
+func (group *Group) Role(name string) (Role, bool) {
+	group.groupMutex.RLock()
+	defer group.groupMutex.RUnlock()
+	role, ok := group.members[name]
+	return role, ok
+}
+
+// Members of the group ordered by name
+func (group *Group) Members() []Member {
+	group.groupMutex.RLock()
+	defer group.groupMutex.RUnlock()
+	members := make([]Member, 0, len(group.members))
+	for name, role := range group.members {
+		members = append(members, Member{Name: name, Role: role})
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Name < members[j].Name
+	})
+	return members
+}
+
+// SetRole add name to the group with role, or change their role if they are
+// already a member. Admins manage players, only the owner can manage admins.
+// Making another member the owner hands over ownership, the previous owner
+// becomes an admin.
+func (group *Group) SetRole(by string, name string, role Role) error {
+	if !role.Valid() {
+		return fmt.Errorf("setrole: invalid role %q", role)
+	} else if name == "" {
+		return errors.New("setrole: missing member name")
+	}
+	group.groupMutex.Lock()
+	defer group.groupMutex.Unlock()
+	if name == by {
+		return errors.New("setrole: cannot change your own role")
+	}
+	if err := group.canManage(by, group.members[name], role); err != nil {
+		return fmt.Errorf("setrole: %w", err)
+	}
+	if role == Owner {
+		group.members[by] = Admin
+	}
+	group.members[name] = role
+	return nil
+}
+
+// RemoveMember remove name from the group, any member can leave except the
+// owner
+func (group *Group) RemoveMember(by string, name string) error {
+	group.groupMutex.Lock()
+	defer group.groupMutex.Unlock()
+	current, ok := group.members[name]
+	if !ok {
+		return fmt.Errorf("removemember: %s is not a member", name)
+	} else if current == Owner {
+		return errors.New("removemember: the owner cannot leave the group")
+	} else if name != by {
+		if err := group.canManage(by, current, current); err != nil {
+			return fmt.Errorf("removemember: %w", err)
+		}
+	}
+	delete(group.members, name)
+	return nil
+}
+
+// canManage if by may change a member with role current to role. Called
+// holding groupMutex.
+func (group *Group) canManage(by string, current Role, role Role) error {
+	byRole := group.members[by]
+	if !byRole.AtLeast(Admin) {
+		return fmt.Errorf("%w, only admins can manage members", ErrNotPermitted)
+	} else if (current.AtLeast(Admin) || role.AtLeast(Admin)) &&
+		byRole != Owner {
+		return fmt.Errorf("%w, only the owner can manage admins",
+			ErrNotPermitted)
+	}
+	return nil
+}
+
+// AddTable add table to the group with id, by must be an admin
+func (group *Group) AddTable(by string, id string, table *model.Table) error {
+	group.groupMutex.Lock()
+	defer group.groupMutex.Unlock()
+	if !group.members[by].AtLeast(Admin) {
+		return fmt.Errorf("addtable: %w, only admins can add tables",
+			ErrNotPermitted)
+	} else if _, ok := group.tables[id]; ok {
+		return fmt.Errorf("addtable: table %s already exists", id)
+	}
+	group.tables[id] = table
+	return nil
+}
+
+// Table the group's table with id, nil if there is none
+func (group *Group) Table(id string) *model.Table {
+	group.groupMutex.RLock()
+	defer group.groupMutex.RUnlock()
+	return group.tables[id]
+}
+
+// TableIDs the IDs of the group's tables in order
+func (group *Group) TableIDs() []string {
+	group.groupMutex.RLock()
+	defer group.groupMutex.RUnlock()
+	ids := make([]string, 0, len(group.tables))
+	for id := range group.tables {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
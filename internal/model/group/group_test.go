@@ -0,0 +1,63 @@
+package group
+
+import (
+	"errors"
+	"testing"
+
+	model "github.com/ekotlikoff/gopoker/internal/model/table"
+)
+
+func TestGroupRoles(t *testing.T) {
+	directory := NewDirectory()
+	if _, err := directory.Create("", "Anna"); err == nil {
+		t.Error("expected a group without a name to fail")
+	}
+	g, err := directory.Create("Friday game", "Anna")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SetRole("Anna", "Joe", Admin); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SetRole("Joe", "Bob", Player); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SetRole("Joe", "Nora", Admin); !errors.Is(err, ErrNotPermitted) {
+		t.Error("expected only the owner to add admins got", err)
+	}
+	if err := g.SetRole("Bob", "Nora", Player); !errors.Is(err, ErrNotPermitted) {
+		t.Error("expected players not to add members got", err)
+	}
+	if err := g.RemoveMember("Joe", "Anna"); err == nil {
+		t.Error("expected the owner not to be removed")
+	}
+	if err := g.RemoveMember("Bob", "Bob"); err != nil {
+		t.Error("expected a player to be able to leave got", err)
+	}
+	if groups := directory.ForMember("Bob"); len(groups) != 0 {
+		t.Error("expected Bob to have left got", groups)
+	}
+	// Anna hands the group over to Joe
+	if err := g.SetRole("Anna", "Joe", Owner); err != nil {
+		t.Fatal(err)
+	}
+	expected := []Member{{"Anna", Admin}, {"Joe", Owner}}
+	members := g.Members()
+	if len(members) != len(expected) {
+		t.Fatal("expected", expected, "got", members)
+	}
+	for i := range expected {
+		if members[i] != expected[i] {
+			t.Error("expected", expected, "got", members)
+		}
+	}
+	if err := g.AddTable("Anna", "main", model.NewTable()); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddTable("Anna", "main", model.NewTable()); err == nil {
+		t.Error("expected a duplicate table to fail")
+	}
+	if ids := g.TableIDs(); len(ids) != 1 || g.Table("main") == nil {
+		t.Error("expected the main table got", ids)
+	}
+}
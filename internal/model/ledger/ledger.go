@@ -0,0 +1,230 @@
+// Package ledger records every movement of chips as double-entry transactions
+// so that player and group balances can be queried and survive restarts.
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// TransactionType what caused chips to move between accounts
+	TransactionType string
+
+	// Entry a change to a single account's balance
+	Entry struct {
+		Account string
+		Amount  int
+	}
+
+	// Transaction a set of entries that sum to zero, chips are never created
+	// or destroyed only moved between accounts
+	Transaction struct {
+		ID      uint64
+		Time    time.Time
+		Type    TransactionType
+		Group   string
+		Memo    string
+		Entries []Entry
+	}
+
+	// Ledger keeps the balance of every account and appends each Transaction
+	// to its Store before applying it
+	Ledger struct {
+		store        Store
+		balances     map[string]int
+		transactions []Transaction
+		nextID       uint64
+		ledgerMutex  sync.RWMutex
+	}
+)
+
+const (
+	// BuyIn chips moved from a player's bankroll to a table
+	BuyIn = TransactionType("buyin")
+	// CashOut chips moved from a table back to a player's bankroll
+	CashOut = TransactionType("cashout")
+	// PotWin chips moved between the players at a table by a hand
+	PotWin = TransactionType("potwin")
+	// Rake chips taken from the winners of a hand by the house
+	Rake = TransactionType("rake")
+	// Adjustment chips added to or removed from a player's bankroll by an
+	// administrator
+	Adjustment = TransactionType("adjustment")
+)
+
+// PlayerAccount the account holding a player's bankroll within a group
+func PlayerAccount(group, name string) string {
+	return "group/" + group + "/player/" + name
+}
+
+// TableAccount the account holding a player's chips at one of the group's
+// tables
+func TableAccount(group, table, name string) string {
+	return "group/" + group + "/table/" + table + "/player/" + name
+}
+
+// RakeAccount the account holding the rake collected by a group's tables
+func RakeAccount(group string) string {
+	return "group/" + group + "/rake"
+}
+
+// EquityAccount the account adjustments to a group's bankrolls are balanced
+// against
+func EquityAccount(group string) string {
+	return "group/" + group + "/equity"
+}
+
+// NewLedger create a ledger backed by store, replaying the transactions
+// already in it. A nil store keeps the ledger in memory only.
+func NewLedger(store Store) (*Ledger, error) {
+	ledger := &Ledger{store: store, balances: make(map[string]int)}
+	if store == nil {
+		return ledger, nil
+	}
+	transactions, err := store.Load()
+	if err != nil {
+		return nil, fmt.Errorf("newledger: %w", err)
+	}
+	for _, transaction := range transactions {
+		if err := transaction.validate(); err != nil {
+			return nil, fmt.Errorf("newledger: transaction %d: %w",
+				transaction.ID, err)
+		}
+		ledger.apply(transaction)
+	}
+	return ledger, nil
+}
+
+func (transaction Transaction) validate() error {
+	if len(transaction.Entries) == 0 {
+		return errors.New("transaction has no entries")
+	}
+	sum := 0
+	for _, entry := range transaction.Entries {
+		sum += entry.Amount
+	}
+	if sum != 0 {
+		return fmt.Errorf("entries sum to %d rather than zero", sum)
+	}
+	return nil
+}
+
+func (ledger *Ledger) apply(transaction Transaction) {
+	for _, entry := range transaction.Entries {
+		ledger.balances[entry.Account] += entry.Amount
+	}
+	ledger.transactions = append(ledger.transactions, transaction)
+	if transaction.ID >= ledger.nextID {
+		ledger.nextID = transaction.ID + 1
+	}
+}
+
+// Record append a transaction to the store and apply it to the balances, the
+// entries must sum to zero
+func (ledger *Ledger) Record(transactionType TransactionType, group string,
+	memo string, entries ...Entry) (Transaction, error) {
+	ledger.ledgerMutex.Lock()
+	defer ledger.ledgerMutex.Unlock()
+	return ledger.record(transactionType, group, memo, entries)
+}
+
+func (ledger *Ledger) record(transactionType TransactionType, group string,
+	memo string, entries []Entry) (Transaction, error) {
+	transaction := Transaction{
+		ID: ledger.nextID, Time: time.Now(), Type: transactionType,
+		Group: group, Memo: memo, Entries: entries,
+	}
+	if err := transaction.validate(); err != nil {
+		return transaction, fmt.Errorf("record: %w", err)
+	}
+	if ledger.store != nil {
+		if err := ledger.store.Append(transaction); err != nil {
+			return transaction, fmt.Errorf("record: %w", err)
+		}
+	}
+	ledger.apply(transaction)
+	return transaction, nil
+}
+
+// Transfer record a transaction moving amount from one account to another,
+// failing if the from account's balance is insufficient
+func (ledger *Ledger) Transfer(transactionType TransactionType, group string,
+	from string, to string, amount int, memo string) error {
+	ledger.ledgerMutex.Lock()
+	defer ledger.ledgerMutex.Unlock()
+	if amount < 0 {
+		return errors.New("transfer: amount must not be negative")
+	} else if balance := ledger.balances[from]; balance < amount {
+		return fmt.Errorf("transfer: insufficient balance in %s, balance=%d "+
+			"amount=%d", from, balance, amount)
+	}
+	_, err := ledger.record(transactionType, group, memo, []Entry{
+		{Account: from, Amount: -amount}, {Account: to, Amount: amount},
+	})
+	return err
+}
+
+// Adjust add amount, which may be negative, to a player's bankroll
+func (ledger *Ledger) Adjust(group, name string, amount int, memo string) error {
+	_, err := ledger.Record(Adjustment, group, memo,
+		Entry{Account: EquityAccount(group), Amount: -amount},
+		Entry{Account: PlayerAccount(group, name), Amount: amount})
+	return err
+}
+
+// Balance the balance of an account
+func (ledger *Ledger) Balance(account string) int {
+	ledger.ledgerMutex.RLock()
+	defer ledger.ledgerMutex.RUnlock()
+	return ledger.balances[account]
+}
+
+// PlayerBalance the chips a player has in a group, both in their bankroll and
+// at the group's tables
+func (ledger *Ledger) PlayerBalance(group, name string) int {
+	ledger.ledgerMutex.RLock()
+	defer ledger.ledgerMutex.RUnlock()
+	balance := ledger.balances[PlayerAccount(group, name)]
+	prefix := "group/" + group + "/table/"
+	for account, amount := range ledger.balances {
+		if strings.HasPrefix(account, prefix) &&
+			strings.HasSuffix(account, "/player/"+name) {
+			balance += amount
+		}
+	}
+	return balance
+}
+
+// GroupBalances the balance of every account in a group
+func (ledger *Ledger) GroupBalances(group string) map[string]int {
+	ledger.ledgerMutex.RLock()
+	defer ledger.ledgerMutex.RUnlock()
+	balances := make(map[string]int)
+	prefix := "group/" + group + "/"
+	for account, amount := range ledger.balances {
+		if strings.HasPrefix(account, prefix) {
+			balances[account] = amount
+		}
+	}
+	return balances
+}
+
+// Transactions the transactions with an entry for account, oldest first
+func (ledger *Ledger) Transactions(account string) []Transaction {
+	ledger.ledgerMutex.RLock()
+	defer ledger.ledgerMutex.RUnlock()
+	out := []Transaction{}
+	for _, transaction := range ledger.transactions {
+		for _, entry := range transaction.Entries {
+			if entry.Account == account {
+				out = append(out, transaction)
+				break
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,163 @@
+package ledger
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	model "github.com/ekotlikoff/gopoker/internal/model/table"
+)
+
+func TestLedgerTableBank(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.jsonl")
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ledger, err := NewLedger(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ledger.Adjust("g", "Anna", 1000, "initial credit")
+	ledger.Adjust("g", "Joe", 1000, "initial credit")
+	table := model.NewTable()
+	ledger.Attach(table, "g", "t")
+	anna, joe := model.NewPlayer("Anna"), model.NewPlayer("Joe")
+	if err := table.BuyIn(anna, 0, 600); err != nil {
+		t.Fatal(err)
+	}
+	if err := table.BuyIn(joe, 1, 1200); err == nil {
+		t.Error("expected buy in larger than the bankroll to fail")
+	}
+	if err := table.BuyIn(joe, 1, 500); err != nil {
+		t.Fatal(err)
+	}
+	bank := table.Bank.(*TableBank)
+	bank.RecordHand(table, &model.HandResult{
+		Rake: 20,
+		Net:  map[*model.Player]int{anna: 300, joe: -320},
+		Pots: []model.PotResult{{
+			Amount: 640, Rake: 20,
+			Winners: []model.PotWinner{{Player: anna, Amount: 620}},
+		}},
+	})
+	if err := bank.Deposit("Anna", 900); err != nil {
+		t.Fatal(err)
+	}
+	if balance := ledger.PlayerBalance("g", "Anna"); balance != 1300 {
+		t.Error("expected Anna's balance to be 1300 got", balance)
+	}
+	if balance := ledger.PlayerBalance("g", "Joe"); balance != 680 {
+		t.Error("expected Joe's balance to be 680 got", balance)
+	}
+	if balance := ledger.Balance(RakeAccount("g")); balance != 20 {
+		t.Error("expected rake of 20 got", balance)
+	}
+	total := 0
+	for _, balance := range ledger.GroupBalances("g") {
+		total += balance
+	}
+	if total != 0 {
+		t.Error("expected the group's accounts to sum to zero got", total)
+	}
+	store.Close()
+	reopened, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := NewLedger(reopened)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance := restored.PlayerBalance("g", "Anna"); balance != 1300 {
+		t.Error("expected restored balance of 1300 got", balance)
+	}
+	if len(restored.Transactions(PlayerAccount("g", "Anna"))) != 3 {
+		t.Error("expected 3 transactions for Anna got",
+			restored.Transactions(PlayerAccount("g", "Anna")))
+	}
+}
+
+func TestLedgerRejectsUnbalancedTransaction(t *testing.T) {
+	ledger, _ := NewLedger(nil)
+	if _, err := ledger.Record(Adjustment, "g", "bad",
+		Entry{Account: PlayerAccount("g", "Anna"), Amount: 100}); err == nil {
+		t.Error("expected an unbalanced transaction to be rejected")
+	}
+}
+
+func TestLedgerDropsTornTransaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.jsonl")
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ledger, err := NewLedger(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ledger.Adjust("g", "Anna", 1000, "initial credit"); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"ID":2,"Kind":`)
+	file.Close()
+	reopened, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := NewLedger(reopened)
+	if err != nil {
+		t.Fatal("expected a torn last transaction to be dropped got", err)
+	}
+	if balance := restored.PlayerBalance("g", "Anna"); balance != 1000 {
+		t.Error("expected restored balance of 1000 got", balance)
+	}
+	if err := restored.Adjust("g", "Anna", 500, "bonus"); err != nil {
+		t.Fatal(err)
+	}
+	reopened.Close()
+	again, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer again.Close()
+	transactions, err := again.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transactions) != 2 {
+		t.Error("expected the torn transaction to be cut from the file got",
+			transactions)
+	}
+}
+
+func TestFileStoreLoadErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.jsonl")
+	if err := os.WriteFile(path, []byte("\n\nnot json\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if _, err := store.Load(); err == nil ||
+		!strings.Contains(err.Error(), "line 3") {
+		t.Error("expected the corrupt line 3 to be reported got", err)
+	}
+	long := bytes.Repeat([]byte("x"), maxTransactionSize+1)
+	if err := os.WriteFile(path, long, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load(); err == nil ||
+		!strings.Contains(err.Error(), "longer than") {
+		t.Error("expected a line over the maximum size to fail got", err)
+	}
+}
@@ -0,0 +1,124 @@
+package ledger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+)
+
+type (
+	// Store persists transactions, it is only ever appended to
+	Store interface {
+		// Append a transaction, it must be durable once this returns
+		Append(transaction Transaction) error
+		// Load every transaction appended so far, oldest first
+		Load() ([]Transaction, error)
+	}
+
+	// FileStore a Store that appends each transaction to a file as a line of
+	// JSON
+	FileStore struct {
+		path       string
+		file       *os.File
+		storeMutex sync.Mutex
+	}
+)
+
+// maxTransactionSize is the longest line FileStore.Load will read
+const maxTransactionSize = 1024 * 1024
+
+// NewFileStore open, or create, the append-only file at path
+func NewFileStore(path string) (*FileStore, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("newfilestore: %w", err)
+	}
+	return &FileStore{path: path, file: file}, nil
+}
+
+// Append write the transaction to the end of the file and sync it to disk
+func (store *FileStore) Append(transaction Transaction) error {
+	store.storeMutex.Lock()
+	defer store.storeMutex.Unlock()
+	line, err := json.Marshal(transaction)
+	if err != nil {
+		return fmt.Errorf("append: %w", err)
+	}
+	if _, err := store.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("append: %w", err)
+	}
+	if err := store.file.Sync(); err != nil {
+		return fmt.Errorf("append: %w", err)
+	}
+	return nil
+}
+
+// Load read every transaction in the file. A last line without its newline
+// is a transaction torn by a crash while it was appended, it was never
+// durable so it is dropped and cut from the file before anything else is
+// appended.
+func (store *FileStore) Load() ([]Transaction, error) {
+	store.storeMutex.Lock()
+	defer store.storeMutex.Unlock()
+	file, err := os.Open(store.path)
+	if err != nil {
+		return nil, fmt.Errorf("load: %w", err)
+	}
+	defer file.Close()
+	transactions := []Transaction{}
+	reader := bufio.NewReaderSize(file, maxTransactionSize)
+	var complete int64
+	for number := 1; ; number++ {
+		line, err := reader.ReadSlice('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				log.Printf("Dropping %d bytes of a torn transaction from %s\n",
+					len(line), store.path)
+				if err := store.truncate(complete); err != nil {
+					return nil, fmt.Errorf("load: %w", err)
+				}
+			}
+			return transactions, nil
+		} else if err == bufio.ErrBufferFull {
+			return nil, fmt.Errorf("load: line %d is longer than %d bytes",
+				number, maxTransactionSize)
+		} else if err != nil {
+			return nil, fmt.Errorf("load: %w", err)
+		}
+		complete += int64(len(line))
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var transaction Transaction
+		if err := json.Unmarshal(line, &transaction); err != nil {
+			return nil, fmt.Errorf("load: line %d: %w", number, err)
+		}
+		transactions = append(transactions, transaction)
+	}
+}
+
+// truncate the file to its first size bytes. Called holding storeMutex.
+func (store *FileStore) truncate(size int64) error {
+	if store.file == nil {
+		return errors.New("store is closed")
+	}
+	return store.file.Truncate(size)
+}
+
+// Close the underlying file
+func (store *FileStore) Close() error {
+	store.storeMutex.Lock()
+	defer store.storeMutex.Unlock()
+	if store.file == nil {
+		return errors.New("close: store is already closed")
+	}
+	err := store.file.Close()
+	store.file = nil
+	return err
+}
@@ -0,0 +1,110 @@
+package ledger
+
+import (
+	"fmt"
+	"log"
+
+	model "github.com/ekotlikoff/gopoker/internal/model/table"
+)
+
+// TableBank is the model.Bank of one of a group's tables, buy ins and cash
+// outs move chips between a player's bankroll and their account at the table
+type TableBank struct {
+	ledger *Ledger
+	group  string
+	table  string
+}
+
+// TableBank the bank for a group's table
+func (ledger *Ledger) TableBank(group, table string) *TableBank {
+	return &TableBank{ledger: ledger, group: group, table: table}
+}
+
+// Attach record the buy ins, cash outs and hands played at table
+func (ledger *Ledger) Attach(table *model.Table, group, tableName string) {
+	bank := ledger.TableBank(group, tableName)
+	table.Bank = bank
+	table.AddHandListener(bank.RecordHand)
+}
+
+// Balance the player's bankroll
+func (bank *TableBank) Balance(name string) int {
+	return bank.ledger.Balance(PlayerAccount(bank.group, name))
+}
+
+// Withdraw buy in for amount, failing if the player's bankroll is insufficient
+func (bank *TableBank) Withdraw(name string, amount int) error {
+	return bank.ledger.Transfer(BuyIn, bank.group,
+		PlayerAccount(bank.group, name), TableAccount(bank.group, bank.table, name),
+		amount, "buy in at "+bank.table)
+}
+
+// Deposit cash out amount, the chips the player is leaving the table with
+func (bank *TableBank) Deposit(name string, amount int) error {
+	_, err := bank.ledger.Record(CashOut, bank.group, "cash out at "+bank.table,
+		Entry{Account: TableAccount(bank.group, bank.table, name), Amount: -amount},
+		Entry{Account: PlayerAccount(bank.group, name), Amount: amount})
+	return err
+}
+
+// RecordHand is a model.HandListener that records the chips won and lost by
+// each player in a hand, and the rake taken from the winners
+func (bank *TableBank) RecordHand(table *model.Table, result *model.HandResult) {
+	rakeShares := rakeShares(result)
+	potWin := []Entry{}
+	for player, net := range result.Net {
+		if gross := net + rakeShares[player]; gross != 0 {
+			potWin = append(potWin, Entry{
+				Account: TableAccount(bank.group, bank.table, player.Name),
+				Amount:  gross,
+			})
+		}
+	}
+	memo := "hand at " + bank.table
+	if len(potWin) > 0 {
+		if _, err := bank.ledger.Record(PotWin, bank.group, memo,
+			potWin...); err != nil {
+			log.Println("ERROR failed to record hand", err)
+		}
+	}
+	if result.Rake == 0 {
+		return
+	}
+	rake := []Entry{{Account: RakeAccount(bank.group), Amount: result.Rake}}
+	for player, share := range rakeShares {
+		rake = append(rake, Entry{
+			Account: TableAccount(bank.group, bank.table, player.Name),
+			Amount:  -share,
+		})
+	}
+	if _, err := bank.ledger.Record(Rake, bank.group,
+		fmt.Sprintf("rake of %d at %s", result.Rake, bank.table),
+		rake...); err != nil {
+		log.Println("ERROR failed to record rake", err)
+	}
+}
+
+// rakeShares attribute the rake taken from each pot to its winners in
+// proportion to the amount they won
+func rakeShares(result *model.HandResult) map[*model.Player]int {
+	shares := make(map[*model.Player]int)
+	for _, pot := range result.Pots {
+		if pot.Rake == 0 || len(pot.Winners) == 0 {
+			continue
+		}
+		won := 0
+		for _, w := range pot.Winners {
+			won += w.Amount
+		}
+		remaining := pot.Rake
+		for i, w := range pot.Winners {
+			share := remaining
+			if i < len(pot.Winners)-1 && won > 0 {
+				share = pot.Rake * w.Amount / won
+			}
+			shares[w.Player] += share
+			remaining -= share
+		}
+	}
+	return shares
+}
@@ -0,0 +1,123 @@
+package payouts
+
+import (
+	"errors"
+	"fmt"
+)
+
+type (
+	// DealKind how a Deal divides the remaining prizes
+	DealKind int
+
+	// Deal a proposed division of the remaining prizes between the players
+	// left in a tournament, it ends the tournament once every player accepts
+	Deal struct {
+		Kind    DealKind
+		Players []string
+		Stacks  []int
+		// Amounts each player is paid, in the order of Players
+		Amounts  []int
+		accepted map[string]bool
+	}
+)
+
+const (
+	// ICMDeal pays each player their ICM equity
+	ICMDeal = DealKind(iota)
+	// ChipChop pays each player the smallest remaining prize and divides the
+	// rest in proportion to their stacks
+	ChipChop = DealKind(iota)
+)
+
+// String the deal kind's string
+func (kind DealKind) String() string {
+	switch kind {
+	case ICMDeal:
+		return "ICM"
+	case ChipChop:
+		return "chip chop"
+	}
+	return fmt.Sprintf("DealKind(%d)", int(kind))
+}
+
+// NewDeal propose dividing prizes, the prizes of the remaining places best
+// first, between players with stacks
+func NewDeal(kind DealKind, players []string, stacks []int,
+	prizes []int) (*Deal, error) {
+	if len(players) != len(stacks) || len(prizes) > len(players) {
+		return nil, errors.New("newdeal: every player needs a stack and at " +
+			"most one prize")
+	}
+	total := 0
+	for _, prize := range prizes {
+		total += prize
+	}
+	var equities []float64
+	var err error
+	switch kind {
+	case ICMDeal:
+		equities, err = ICM(stacks, prizes)
+	case ChipChop:
+		equities, err = chipChop(stacks, prizes, total)
+	default:
+		err = fmt.Errorf("unknown deal kind %d", kind)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("newdeal: %w", err)
+	}
+	return &Deal{
+		Kind: kind, Players: players, Stacks: stacks,
+		Amounts: round(equities, total), accepted: make(map[string]bool),
+	}, nil
+}
+
+func chipChop(stacks []int, prizes []int, total int) ([]float64, error) {
+	chips := 0
+	for _, stack := range stacks {
+		if stack < 0 {
+			return nil, errors.New("chipchop: stacks cannot be negative")
+		}
+		chips += stack
+	}
+	if chips == 0 {
+		return nil, errors.New("chipchop: there are no chips")
+	}
+	guaranteed := 0
+	if len(prizes) == len(stacks) {
+		guaranteed = prizes[len(prizes)-1]
+	}
+	shared := total - guaranteed*len(stacks)
+	equities := make([]float64, len(stacks))
+	for i, stack := range stacks {
+		equities[i] = float64(guaranteed) +
+			float64(shared)*float64(stack)/float64(chips)
+	}
+	return equities, nil
+}
+
+// Accept the deal on behalf of the player, returning whether every player has
+// now accepted
+func (deal *Deal) Accept(name string) (bool, error) {
+	for _, player := range deal.Players {
+		if player == name {
+			deal.accepted[name] = true
+			return deal.Accepted(), nil
+		}
+	}
+	return false, errors.New("accept: player is not part of the deal")
+}
+
+// Accepted whether every player has accepted the deal
+func (deal *Deal) Accepted() bool {
+	return len(deal.accepted) == len(deal.Players)
+}
+
+// Amount the player is paid by the deal
+func (deal *Deal) Amount(name string) int {
+	for i, player := range deal.Players {
+		if player == name {
+			return deal.Amounts[i]
+		}
+	}
+	return 0
+}
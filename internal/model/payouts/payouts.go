@@ -0,0 +1,130 @@
+// Package payouts calculates how a tournament's prize pool is divided, both
+// the payout structure by finishing place and the Independent Chip Model
+// equity of the remaining players' stacks when they want to make a deal.
+package payouts
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// MaxICMPlayers above which ICM equities are too expensive to calculate
+const MaxICMPlayers = 16
+
+// Structure the percentage of the prize pool paid to each place when
+// percentPaid percent of entrants are paid, best place first. Every paid place
+// gets at least 1 percent, the rest is weighted by 1/place and any remainder
+// from rounding goes to first.
+func Structure(entrants int, percentPaid int) ([]int, error) {
+	if entrants <= 0 {
+		return nil, errors.New("structure: there must be at least one entrant")
+	} else if percentPaid <= 0 || percentPaid > 100 {
+		return nil, errors.New("structure: percent paid must be between 1 and 100")
+	}
+	paid := (entrants*percentPaid + 99) / 100
+	if paid > 100 {
+		return nil, fmt.Errorf("structure: cannot pay %d places", paid)
+	}
+	weights, total := make([]float64, paid), 0.0
+	for i := range weights {
+		weights[i] = 1 / float64(i+1)
+		total += weights[i]
+	}
+	percentages, sum := make([]int, paid), 0
+	for i, weight := range weights {
+		percentages[i] = 1 + int(float64(100-paid)*weight/total)
+		sum += percentages[i]
+	}
+	percentages[0] += 100 - sum
+	return percentages, nil
+}
+
+// Amounts the chips paid to each place of prizePool by percentages, best
+// place first. Any remainder from rounding goes to first.
+func Amounts(prizePool int, percentages []int) []int {
+	amounts, paid := make([]int, len(percentages)), 0
+	for i, percentage := range percentages {
+		amounts[i] = prizePool * percentage / 100
+		paid += amounts[i]
+	}
+	if len(amounts) > 0 {
+		amounts[0] += prizePool - paid
+	}
+	return amounts
+}
+
+// ICM each stack's equity in prizes, best place first, by the Malmuth-Harville
+// model where the chance of finishing in a place is the stack's share of the
+// chips not held by the players already placed above it
+func ICM(stacks []int, prizes []int) ([]float64, error) {
+	if len(stacks) == 0 || len(stacks) > MaxICMPlayers {
+		return nil, fmt.Errorf("icm: there must be between 1 and %d stacks",
+			MaxICMPlayers)
+	}
+	total := 0
+	for _, stack := range stacks {
+		if stack < 0 {
+			return nil, errors.New("icm: stacks cannot be negative")
+		}
+		total += stack
+	}
+	if total == 0 {
+		return nil, errors.New("icm: there are no chips")
+	}
+	places := len(prizes)
+	if places > len(stacks) {
+		places = len(stacks)
+	}
+	// probability[placed] the chance the players in the placed bit set
+	// finished in the top places, in some order
+	probability := make([]float64, 1<<len(stacks))
+	probability[0] = 1
+	equities := make([]float64, len(stacks))
+	for placed := 0; placed < len(probability); placed++ {
+		if probability[placed] == 0 {
+			continue
+		}
+		place, chipsLeft := 0, total
+		for i, stack := range stacks {
+			if placed&(1<<i) != 0 {
+				place++
+				chipsLeft -= stack
+			}
+		}
+		if place == places || chipsLeft == 0 {
+			continue
+		}
+		for i, stack := range stacks {
+			if placed&(1<<i) != 0 {
+				continue
+			}
+			p := probability[placed] * float64(stack) / float64(chipsLeft)
+			equities[i] += p * float64(prizes[place])
+			probability[placed|1<<i] += p
+		}
+	}
+	return equities, nil
+}
+
+// round equities down to whole chips and give the chips left of total to
+// the equities that lost the most by rounding
+func round(equities []float64, total int) []int {
+	amounts, paid := make([]int, len(equities)), 0
+	for i, equity := range equities {
+		amounts[i] = int(math.Floor(equity))
+		paid += amounts[i]
+	}
+	for paid < total {
+		largest := 0
+		for i := range amounts {
+			if equities[i]-float64(amounts[i]) >
+				equities[largest]-float64(amounts[largest]) {
+				largest = i
+			}
+		}
+		amounts[largest]++
+		paid++
+	}
+	return amounts
+}
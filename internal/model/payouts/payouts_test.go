@@ -0,0 +1,90 @@
+package payouts
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStructure(t *testing.T) {
+	percentages, err := Structure(30, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(percentages) != 3 {
+		t.Fatal("expected 3 places paid got", percentages)
+	}
+	total := 0
+	for i, percentage := range percentages {
+		if i > 0 && percentage > percentages[i-1] {
+			t.Error("expected payouts to decrease by place got", percentages)
+		}
+		total += percentage
+	}
+	if total != 100 {
+		t.Error("expected percentages to sum to 100 got", percentages)
+	}
+	if _, err := Structure(1000, 50); err == nil {
+		t.Error("expected paying 500 places to fail")
+	}
+	amounts := Amounts(1001, []int{50, 30, 20})
+	if amounts[0] != 501 || amounts[1] != 300 || amounts[2] != 200 {
+		t.Error("expected the remainder to go to first got", amounts)
+	}
+}
+
+func TestICM(t *testing.T) {
+	equities, err := ICM([]int{50, 30, 20}, []int{50, 30, 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(equities[0]-38.39) > 0.01 {
+		t.Error("expected the chip leader's equity to be 38.39 got", equities[0])
+	}
+	total := 0.0
+	for _, equity := range equities {
+		total += equity
+	}
+	if math.Abs(total-100) > 1e-9 {
+		t.Error("expected the equities to sum to the prizes got", total)
+	}
+	equities, _ = ICM([]int{100, 100, 100, 100}, []int{60, 40})
+	for _, equity := range equities {
+		if math.Abs(equity-25) > 1e-9 {
+			t.Error("expected equal stacks to have equal equity got", equities)
+		}
+	}
+}
+
+func TestDeal(t *testing.T) {
+	players := []string{"Anna", "Joe", "Bob"}
+	deal, err := NewDeal(ChipChop, players, []int{6000, 3000, 1000},
+		[]int{500, 300, 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Everyone is guaranteed 200 and the remaining 400 is shared by stack
+	if deal.Amount("Anna") != 440 || deal.Amount("Joe") != 320 ||
+		deal.Amount("Bob") != 240 {
+		t.Error("expected a chip chop of 440, 320 and 240 got", deal.Amounts)
+	}
+	deal, err = NewDeal(ICMDeal, players, []int{5000, 3000, 2000},
+		[]int{500, 300, 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deal.Amount("Anna") != 384 ||
+		deal.Amounts[0]+deal.Amounts[1]+deal.Amounts[2] != 1000 {
+		t.Error("expected an ICM deal of the 1000 prize pool got", deal.Amounts)
+	}
+	if _, err := deal.Accept("Nora"); err == nil {
+		t.Error("expected a player outside the deal not to be able to accept")
+	}
+	for i, player := range players {
+		agreed, err := deal.Accept(player)
+		if err != nil {
+			t.Fatal(err)
+		} else if agreed != (i == len(players)-1) {
+			t.Error("expected the deal to be agreed once everyone accepts")
+		}
+	}
+}
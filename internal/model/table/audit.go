@@ -0,0 +1,96 @@
+package model
+
+import (
+	"fmt"
+	"log"
+)
+
+type (
+	// AuditMode controls whether chip conservation is checked during a Hand
+	AuditMode int
+
+	// AuditViolation chips were created or destroyed during a Hand, the sum
+	// of stacks, outstanding bets, pots and rake no longer equals the chips the
+	// players started the hand with
+	AuditViolation struct {
+		// Stage of the hand that was being audited, e.g. "action by Anna"
+		Stage    string
+		Expected int
+		Actual   int
+		// State of every player dealt in and of the pots when it was detected
+		State string
+	}
+)
+
+const (
+	// AuditOff chips are not audited
+	AuditOff = AuditMode(iota)
+	// AuditLog violations are logged and play continues
+	AuditLog = AuditMode(iota)
+	// AuditStrict violations are logged and stop play at the table
+	AuditStrict = AuditMode(iota)
+)
+
+func (violation *AuditViolation) Error() string {
+	return fmt.Sprintf("audit: chip conservation violated at %s, expected=%d "+
+		"actual=%d\n%s", violation.Stage, violation.Expected, violation.Actual,
+		violation.State)
+}
+
+// chipTotal the chips held by the players dealt in, whether in their stack,
+// bet or in a pot, plus any rake taken
+func (hand *Hand) chipTotal() int {
+	total := hand.Pot.Total()
+	for _, bet := range hand.Round.foldedBets {
+		total += bet
+	}
+	for _, p := range hand.DealtIn {
+		total += p.Funds + p.BetAmount
+	}
+	if hand.Result != nil {
+		total += hand.Result.Rake
+	}
+	return total
+}
+
+func (hand *Hand) auditState() string {
+	out := ""
+	for _, p := range hand.DealtIn {
+		out += fmt.Sprintf("%s, Funds: %d, BetAmount: %d, AllIn: %t\n",
+			p.Name, p.Funds, p.BetAmount, p.AllIn)
+	}
+	if len(hand.Round.foldedBets) > 0 {
+		out += fmt.Sprintf("FoldedBets=%v\n", hand.Round.foldedBets)
+	}
+	for i, pot := range hand.Pot.SidePots {
+		out += fmt.Sprintf("SidePot %d=%d, player_count=%d\n", i, pot.Pot,
+			len(pot.Players))
+	}
+	out += fmt.Sprintf("MainPot=%d, player_count=%d\n", hand.Pot.MainPot.Pot,
+		len(hand.Pot.MainPot.Players))
+	if hand.Result != nil {
+		out += fmt.Sprintf("Rake=%d\n", hand.Result.Rake)
+	}
+	return out
+}
+
+// audit checks that no chips have been created or destroyed since the hand
+// started, returning an error only in AuditStrict mode
+func (hand *Hand) audit(stage string) error {
+	if hand.TableConfig.audit == AuditOff {
+		return nil
+	}
+	actual := hand.chipTotal()
+	if actual == hand.startingChips {
+		return nil
+	}
+	violation := &AuditViolation{
+		Stage: stage, Expected: hand.startingChips, Actual: actual,
+		State: hand.auditState(),
+	}
+	log.Println(violation)
+	if hand.TableConfig.audit == AuditStrict {
+		return violation
+	}
+	return nil
+}
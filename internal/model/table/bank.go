@@ -0,0 +1,224 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+type (
+	// Bank holds the chips players have away from the table, buy ins are
+	// withdrawn from it and cash outs are deposited back into it
+	Bank interface {
+		// Balance the chips the player has in the bank
+		Balance(name string) int
+		// Withdraw chips from the player's balance, failing if it is
+		// insufficient
+		Withdraw(name string, amount int) error
+		// Deposit chips into the player's balance
+		Deposit(name string, amount int) error
+	}
+
+	// MemoryBank a Bank whose balances only live in memory
+	MemoryBank struct {
+		balances  map[string]int
+		bankMutex sync.Mutex
+	}
+)
+
+// NewMemoryBank create an empty bank
+func NewMemoryBank() *MemoryBank {
+	return &MemoryBank{balances: make(map[string]int)}
+}
+
+// Balance the chips the player has in the bank
+func (bank *MemoryBank) Balance(name string) int {
+	bank.bankMutex.Lock()
+	defer bank.bankMutex.Unlock()
+	return bank.balances[name]
+}
+
+// Withdraw chips from the player's balance
+func (bank *MemoryBank) Withdraw(name string, amount int) error {
+	bank.bankMutex.Lock()
+	defer bank.bankMutex.Unlock()
+	if amount < 0 {
+		return errors.New("withdraw: amount must not be negative")
+	} else if bank.balances[name] < amount {
+		return fmt.Errorf("withdraw: insufficient balance, balance=%d amount=%d",
+			bank.balances[name], amount)
+	}
+	bank.balances[name] -= amount
+	return nil
+}
+
+// Deposit chips into the player's balance
+func (bank *MemoryBank) Deposit(name string, amount int) error {
+	bank.bankMutex.Lock()
+	defer bank.bankMutex.Unlock()
+	if amount < 0 {
+		return errors.New("deposit: amount must not be negative")
+	}
+	bank.balances[name] += amount
+	return nil
+}
+
+func (table *Table) validBuyIn(player *Player) error {
+	config := table.TableConfig
+	funds := player.Funds
+	if stack := table.ratholeStack(player); funds < stack {
+		return fmt.Errorf("buy in of %d is less than the %d %s left with "+
+			"within the last %s", funds, stack, player.Name, config.ratholeWindow)
+	} else if config.minBuyIn > 0 && funds < config.minBuyIn {
+		return fmt.Errorf("buy in of %d is less than the minimum, %d", funds,
+			config.minBuyIn)
+	} else if config.maxBuyIn > 0 && funds > config.maxBuyIn && funds > stack {
+		return fmt.Errorf("buy in of %d is more than the maximum, %d", funds,
+			config.maxBuyIn)
+	}
+	return nil
+}
+
+// recordDeparture remember the stack of a player leaving the table so they
+// cannot return with fewer chips within the rathole window
+func (table *Table) recordDeparture(player *Player) {
+	stack := player.Funds + player.pendingChips
+	if table.TableConfig.ratholeWindow > 0 && stack > 0 {
+		table.departures[player.Name] = departure{stack: stack, at: time.Now()}
+	}
+}
+
+// ratholeStack the minimum the player must return to the table with, zero if
+// they have not left within the rathole window
+func (table *Table) ratholeStack(player *Player) int {
+	left, ok := table.departures[player.Name]
+	if !ok {
+		return 0
+	} else if time.Since(left.at) > table.TableConfig.ratholeWindow {
+		delete(table.departures, player.Name)
+		return 0
+	}
+	return left.stack
+}
+
+// BuyIn withdraw amount from the player's bank balance and sit them at seat
+func (table *Table) BuyIn(player *Player, seat int, amount int) error {
+	if table.Bank == nil {
+		return errors.New("buyin: table has no bank")
+	}
+	return table.do(func() error {
+		return table.buyIn(player, seat, amount)
+	})
+}
+
+func (table *Table) buyIn(player *Player, seat int, amount int) error {
+	if player.Funds != 0 {
+		return errors.New("buyin: player already has chips")
+	}
+	if err := table.Bank.Withdraw(player.Name, amount); err != nil {
+		return fmt.Errorf("buyin: %w", err)
+	}
+	player.Funds = amount
+	if err := table.sitDown(player, seat); err != nil {
+		player.Funds = 0
+		if depositErr := table.Bank.Deposit(player.Name,
+			amount); depositErr != nil {
+			log.Println("ERROR failed to return buy in", depositErr)
+		}
+		return fmt.Errorf("buyin: %w", err)
+	}
+	return nil
+}
+
+// TopUp withdraw amount from the player's bank balance and add it to their
+// stack, a player that has busted can use this to rebuy. The chips are added
+// immediately if no hand is being played, otherwise before the next hand.
+func (table *Table) TopUp(player *Player, amount int) error {
+	return table.do(func() error {
+		return table.topUp(player, amount)
+	})
+}
+
+func (table *Table) topUp(player *Player, amount int) error {
+	if table.Bank == nil {
+		return errors.New("topup: table has no bank")
+	} else if amount <= 0 {
+		return errors.New("topup: amount must be positive")
+	} else if !table.isSeated(player) {
+		return errors.New("topup: player is not sitting at this table")
+	}
+	stack := player.Funds + player.pendingChips + amount
+	if maxBuyIn := table.TableConfig.maxBuyIn; maxBuyIn > 0 && stack > maxBuyIn {
+		return fmt.Errorf("topup: stack of %d would exceed the maximum buy in, %d",
+			stack, maxBuyIn)
+	}
+	if err := table.Bank.Withdraw(player.Name, amount); err != nil {
+		return fmt.Errorf("topup: %w", err)
+	}
+	player.pendingChips += amount
+	if !table.playing {
+		table.applyTopUp(player)
+	}
+	return nil
+}
+
+// AddChips give the seated player amount chips paid for outside the table's
+// bank, e.g. a tournament add on, added between hands like a top up
+func (table *Table) AddChips(player *Player, amount int) error {
+	return table.do(func() error {
+		if amount <= 0 {
+			return errors.New("addchips: amount must be positive")
+		} else if !table.isSeated(player) {
+			return errors.New("addchips: player is not sitting at this table")
+		}
+		player.pendingChips += amount
+		if !table.playing {
+			table.applyTopUp(player)
+		}
+		return nil
+	})
+}
+
+func (table *Table) isSeated(player *Player) bool {
+	for _, p := range table.Players {
+		if p == player {
+			return true
+		}
+	}
+	return false
+}
+
+func (table *Table) applyTopUp(player *Player) {
+	if player.pendingChips > 0 {
+		log.Println(player.Name, "topped up", player.pendingChips)
+		player.Funds += player.pendingChips
+		player.pendingChips = 0
+	}
+}
+
+// applyTopUps adds pending top ups to the stacks of seated players, only to
+// be called between hands
+func (table *Table) applyTopUps() {
+	table.tableMutex.Lock()
+	defer table.tableMutex.Unlock()
+	for _, p := range table.Players {
+		if p != nil {
+			table.applyTopUp(p)
+		}
+	}
+}
+
+// cashOut return the chips of a player leaving the table to the bank
+func (table *Table) cashOut(player *Player) error {
+	if table.Bank == nil {
+		return nil
+	}
+	chips := player.Funds + player.pendingChips
+	player.Funds, player.pendingChips = 0, 0
+	if err := table.Bank.Deposit(player.Name, chips); err != nil {
+		return fmt.Errorf("cashout: %w", err)
+	}
+	return nil
+}
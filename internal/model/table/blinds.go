@@ -0,0 +1,195 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+type (
+	// BlindLevel is one level of a blind schedule
+	BlindLevel struct {
+		SmallBlind int
+		BigBlind   int
+		Ante       int
+		// Duration the level lasts, if zero the level lasts for Hands hands
+		Duration time.Duration
+		Hands    int
+		// Break from play for Duration rather than a level of blinds
+		Break bool
+	}
+
+	// BlindInfo describes where a Table is in its blind schedule
+	BlindInfo struct {
+		// Level is the index of the current level in the schedule
+		Level   int
+		Current BlindLevel
+		// Next level, nil if the current level is the last
+		Next *BlindLevel
+		// TimeRemaining in the current level, for levels that last a Duration
+		TimeRemaining time.Duration
+		// HandsRemaining in the current level, for levels that last Hands
+		HandsRemaining int
+	}
+
+	// blindState tracks a Table's progress through its blind schedule
+	blindState struct {
+		level        int
+		levelStarted time.Time
+		handsPlayed  int
+	}
+)
+
+// smallBlindAmount the small blind, half the big blind unless configured
+func (config TableConfig) smallBlindAmount() int {
+	if config.smallBlind > 0 {
+		return config.smallBlind
+	}
+	return config.minBet / 2
+}
+
+// WithBlindSchedule a copy of the config whose blinds increase between hands
+// through the levels of the schedule, staying at the last level once reached
+func (config TableConfig) WithBlindSchedule(levels []BlindLevel) (TableConfig,
+	error) {
+	if err := validBlindSchedule(levels); err != nil {
+		return config, err
+	}
+	config.blindLevels = levels
+	config = config.withBlindLevel(levels[0])
+	return config, nil
+}
+
+func validBlindSchedule(levels []BlindLevel) error {
+	if len(levels) == 0 {
+		return errors.New("blind schedule has no levels")
+	} else if levels[0].Break || levels[len(levels)-1].Break {
+		return errors.New("blind schedule cannot start or end with a break")
+	}
+	for i, level := range levels {
+		if level.Duration <= 0 && (level.Break || level.Hands <= 0) {
+			return fmt.Errorf("level %d has no duration", i)
+		} else if !level.Break && (level.BigBlind <= 0 ||
+			level.SmallBlind <= 0 || level.SmallBlind > level.BigBlind ||
+			level.Ante < 0) {
+			return fmt.Errorf("level %d has invalid blinds, sb=%d bb=%d ante=%d",
+				i, level.SmallBlind, level.BigBlind, level.Ante)
+		}
+	}
+	return nil
+}
+
+func (config TableConfig) withBlindLevel(level BlindLevel) TableConfig {
+	config.minBet = level.BigBlind
+	config.smallBlind = level.SmallBlind
+	config.ante = level.Ante
+	return config
+}
+
+// levelOver if the current level has lasted its duration or number of hands
+func (table *Table) levelOver(now time.Time) bool {
+	level := table.TableConfig.blindLevels[table.blinds.level]
+	if level.Duration > 0 {
+		return now.Sub(table.blinds.levelStarted) >= level.Duration
+	}
+	return table.blinds.handsPlayed >= level.Hands
+}
+
+// nextBlindLevel moves to the next level of the schedule once the current one
+// is over, returning the level if it is a break. Only called between hands.
+func (table *Table) nextBlindLevel(now time.Time) *BlindLevel {
+	table.tableMutex.Lock()
+	defer table.tableMutex.Unlock()
+	levels := table.TableConfig.blindLevels
+	if len(levels) == 0 {
+		return nil
+	}
+	if table.blinds.level == len(levels)-1 || !table.levelOver(now) {
+		return nil
+	}
+	table.blinds = blindState{level: table.blinds.level + 1, levelStarted: now}
+	level := levels[table.blinds.level]
+	if level.Break {
+		log.Println("Blinds are on a break for", level.Duration)
+		return &level
+	}
+	log.Printf("Blinds are now %d/%d ante %d\n", level.SmallBlind,
+		level.BigBlind, level.Ante)
+	table.TableConfig = table.TableConfig.withBlindLevel(level)
+	return nil
+}
+
+// advanceBlinds count the hand just played towards the current level and move
+// through the schedule, sitting out any breaks while making queued changes,
+// returning ctx's error if it is done during one. Only called between hands.
+func (table *Table) advanceBlinds(ctx context.Context) error {
+	table.tableMutex.Lock()
+	table.blinds.handsPlayed++
+	table.tableMutex.Unlock()
+	for {
+		breakLevel := table.nextBlindLevel(time.Now())
+		if breakLevel == nil {
+			return nil
+		}
+		table.sleep(ctx, breakLevel.Duration)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// startBlindClock start timing the current level if it has not been started
+func (table *Table) startBlindClock() {
+	table.tableMutex.Lock()
+	defer table.tableMutex.Unlock()
+	if table.blinds.levelStarted.IsZero() {
+		table.blinds.levelStarted = time.Now()
+	}
+}
+
+// BlindInfo the current and next level of the table's blind schedule, nil if
+// the table has no schedule
+func (table *Table) BlindInfo() *BlindInfo {
+	table.tableMutex.RLock()
+	defer table.tableMutex.RUnlock()
+	levels := table.TableConfig.blindLevels
+	if len(levels) == 0 {
+		return nil
+	}
+	current := levels[table.blinds.level]
+	info := &BlindInfo{Level: table.blinds.level, Current: current}
+	if table.blinds.level < len(levels)-1 {
+		next := levels[table.blinds.level+1]
+		info.Next = &next
+	}
+	if current.Duration > 0 {
+		info.TimeRemaining = current.Duration
+		if !table.blinds.levelStarted.IsZero() {
+			info.TimeRemaining -= time.Since(table.blinds.levelStarted)
+		}
+		if info.TimeRemaining < 0 {
+			info.TimeRemaining = 0
+		}
+	} else {
+		info.HandsRemaining = current.Hands - table.blinds.handsPlayed
+		if info.HandsRemaining < 0 {
+			info.HandsRemaining = 0
+		}
+	}
+	return info
+}
+
+// SyncBlinds move the table to the same point of the blind schedule as other,
+// e.g. when a tournament opens a table part way through. Only called before
+// the table plays.
+func (table *Table) SyncBlinds(other *Table) {
+	other.tableMutex.RLock()
+	config, blinds := other.TableConfig, other.blinds
+	other.tableMutex.RUnlock()
+	table.tableMutex.Lock()
+	defer table.tableMutex.Unlock()
+	table.TableConfig = config
+	table.blinds = blinds
+}
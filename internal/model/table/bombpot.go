@@ -0,0 +1,121 @@
+package model
+
+import (
+	"errors"
+	"log"
+)
+
+type (
+	// BombPotConfig defines when a Table deals bomb pots, hands where every
+	// player antes and play starts on the flop with no preflop betting
+	BombPotConfig struct {
+		// Ante every player puts in the pot
+		Ante int
+		// Every a bomb pot is dealt every this many hands, zero for none
+		// scheduled
+		Every int
+		// EveryOrbit a bomb pot is dealt once the button has passed every
+		// seated player since the last
+		EveryOrbit bool
+		// DoubleBoard deal bomb pots on two boards
+		DoubleBoard bool
+	}
+
+	// bombPotState tracks when a Table's next bomb pot is due
+	bombPotState struct {
+		handsSince int
+		scheduled  bool
+		votes      map[*Player]struct{}
+	}
+)
+
+// WithBombPots a copy of the config that deals bomb pots, the button does not
+// move after a bomb pot so nobody skips or pays the blinds twice
+func (config TableConfig) WithBombPots(bombPots BombPotConfig) (TableConfig,
+	error) {
+	if bombPots.Ante <= 0 || bombPots.Every < 0 {
+		return config, errors.New("bomb pots need an ante")
+	}
+	config.bombPots = bombPots
+	return config, nil
+}
+
+// ScheduleBombPot make the next hand dealt at the table a bomb pot
+func (table *Table) ScheduleBombPot() error {
+	return table.do(func() error {
+		if table.TableConfig.bombPots.Ante == 0 {
+			return errors.New("schedulebombpot: table does not deal bomb pots")
+		}
+		table.bombPot.scheduled = true
+		return nil
+	})
+}
+
+// VoteBombPot the player votes for the next hand to be a bomb pot, it is
+// scheduled once more than half of the seated players have voted
+func (table *Table) VoteBombPot(player *Player) error {
+	return table.do(func() error {
+		return table.voteBombPot(player)
+	})
+}
+
+func (table *Table) voteBombPot(player *Player) error {
+	if table.TableConfig.bombPots.Ante == 0 {
+		return errors.New("votebombpot: table does not deal bomb pots")
+	} else if !table.isSeated(player) {
+		return errors.New("votebombpot: player is not sitting at this table")
+	}
+	if table.bombPot.votes == nil {
+		table.bombPot.votes = make(map[*Player]struct{})
+	}
+	table.bombPot.votes[player] = struct{}{}
+	seated := table.seatedCount()
+	log.Println(player.Name, "voted for a bomb pot,", len(table.bombPot.votes),
+		"of", seated)
+	if len(table.bombPot.votes)*2 > seated {
+		table.bombPot.scheduled = true
+		table.bombPot.votes = nil
+	}
+	return nil
+}
+
+func (table *Table) seatedCount() int {
+	seated := 0
+	for _, p := range table.Players {
+		if p != nil {
+			seated++
+		}
+	}
+	return seated
+}
+
+// nextHandIsBombPot whether the hand about to be dealt is a bomb pot, only
+// called between hands
+func (table *Table) nextHandIsBombPot() bool {
+	table.tableMutex.Lock()
+	defer table.tableMutex.Unlock()
+	config := table.TableConfig.bombPots
+	if config.Ante == 0 {
+		return false
+	}
+	state := &table.bombPot
+	due := state.scheduled ||
+		config.Every > 0 && state.handsSince+1 >= config.Every ||
+		config.EveryOrbit && state.handsSince >= table.seatedCount()
+	if due {
+		state.scheduled = false
+		state.votes = nil
+		state.handsSince = 0
+	} else {
+		state.handsSince++
+	}
+	return due
+}
+
+// takeBombPot every player antes, see postAntes, and the flop is dealt on
+// every board
+func (hand *Hand) takeBombPot() {
+	hand.record("*** BOMB POT ***")
+	hand.postAntes(hand.TableConfig.bombPots.Ante, "bomb pot ante")
+	hand.dealBoards(3)
+}
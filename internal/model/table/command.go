@@ -0,0 +1,108 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+// command a change to a Table queued for the goroutine playing it, which
+// makes the change at the next point it is waiting, e.g. for a player's
+// action or between hands
+type command struct {
+	table  *Table
+	apply  func() error
+	result chan error
+}
+
+// run the command holding the table's lock and send back its result
+func (cmd *command) run() {
+	cmd.table.tableMutex.Lock()
+	err := cmd.apply()
+	cmd.table.tableMutex.Unlock()
+	cmd.result <- err
+}
+
+// do make a change to the table, waiting for its result. If the table is
+// playing the change is queued for the goroutine playing it, otherwise, or
+// while that goroutine is calling the table's listeners, the change is made
+// immediately. apply is called holding tableMutex.
+func (table *Table) do(apply func() error) error {
+	for {
+		table.tableMutex.Lock()
+		if !table.playing || table.listening {
+			defer table.tableMutex.Unlock()
+			return apply()
+		}
+		released := table.released
+		table.tableMutex.Unlock()
+		cmd := &command{table: table, apply: apply, result: make(chan error, 1)}
+		select {
+		case table.commands <- cmd:
+			return <-cmd.result
+		case <-released:
+		}
+	}
+}
+
+// startCommands queue changes for the goroutine playing the table
+func (table *Table) startCommands() {
+	table.released = make(chan struct{})
+}
+
+// stopCommands make changes immediately, once the table has stopped playing
+// or while it calls its listeners. Called holding tableMutex.
+func (table *Table) stopCommands() {
+	if table.released != nil {
+		close(table.released)
+		table.released = nil
+	}
+}
+
+// callListeners call the table's listeners, they and anything else changing
+// the table while they are called do so immediately rather than waiting for
+// the goroutine calling them
+func (table *Table) callListeners(call func()) {
+	table.tableMutex.Lock()
+	table.listening = true
+	table.stopCommands()
+	table.tableMutex.Unlock()
+	call()
+	table.tableMutex.Lock()
+	table.listening = false
+	table.startCommands()
+	table.tableMutex.Unlock()
+}
+
+// sleep make queued changes for d or until ctx is done
+func (table *Table) sleep(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	for {
+		select {
+		case cmd := <-table.commands:
+			cmd.run()
+		case <-timer.C:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// waitWhilePaused make queued changes until play is not paused, or return
+// ctx's error once it is done
+func (table *Table) waitWhilePaused(ctx context.Context) error {
+	for {
+		paused, changed := table.pause.state()
+		if !paused {
+			return nil
+		}
+		select {
+		case cmd := <-table.commands:
+			cmd.run()
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
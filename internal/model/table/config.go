@@ -0,0 +1,151 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ConfigChange changes to a Table's config, nil fields are left unchanged
+type ConfigChange struct {
+	SmallBlind             *int
+	BigBlind               *int
+	Ante                   *int
+	TimeToBet              *time.Duration
+	TimeBetweenHands       *time.Duration
+	AllowBlindModification *bool
+	DisconnectProtection   *DisconnectProtection
+}
+
+// ModifyConfig change the table's config, the change is applied immediately
+// if the table is not playing and before the next hand otherwise. The config
+// the table will play the next hand with is returned.
+func (table *Table) ModifyConfig(change ConfigChange) (ConfigState, error) {
+	var state ConfigState
+	err := table.do(func() error {
+		var err error
+		state, err = table.modifyConfig(change)
+		return err
+	})
+	return state, err
+}
+
+func (table *Table) modifyConfig(change ConfigChange) (ConfigState, error) {
+	pending := change
+	if table.pendingConfig != nil {
+		pending = table.pendingConfig.merge(change)
+	}
+	config := pending.apply(table.TableConfig)
+	if change.changesBlinds() && len(config.blindLevels) > 0 {
+		return table.TableConfig.state(),
+			errors.New("modifyconfig: table follows a blind schedule")
+	} else if err := config.valid(); err != nil {
+		return table.TableConfig.state(), fmt.Errorf("modifyconfig: %w", err)
+	}
+	if table.playing {
+		table.pendingConfig = &pending
+	} else {
+		table.TableConfig = config
+	}
+	return config.state(), nil
+}
+
+// ModifyBlinds change the table's blinds and ante, see ModifyConfig
+func (table *Table) ModifyBlinds(smallBlind int, bigBlind int, ante int) error {
+	_, err := table.ModifyConfig(ConfigChange{
+		SmallBlind: &smallBlind, BigBlind: &bigBlind, Ante: &ante,
+	})
+	return err
+}
+
+// applyPendingConfig apply any config changes made while a hand was played
+func (table *Table) applyPendingConfig() {
+	table.tableMutex.Lock()
+	defer table.tableMutex.Unlock()
+	if table.pendingConfig != nil {
+		table.TableConfig = table.pendingConfig.apply(table.TableConfig)
+		table.pendingConfig = nil
+	}
+}
+
+func (change ConfigChange) changesBlinds() bool {
+	return change.SmallBlind != nil || change.BigBlind != nil ||
+		change.Ante != nil
+}
+
+// merge a later change into this one
+func (change ConfigChange) merge(later ConfigChange) ConfigChange {
+	if later.SmallBlind != nil {
+		change.SmallBlind = later.SmallBlind
+	}
+	if later.BigBlind != nil {
+		change.BigBlind = later.BigBlind
+	}
+	if later.Ante != nil {
+		change.Ante = later.Ante
+	}
+	if later.TimeToBet != nil {
+		change.TimeToBet = later.TimeToBet
+	}
+	if later.TimeBetweenHands != nil {
+		change.TimeBetweenHands = later.TimeBetweenHands
+	}
+	if later.AllowBlindModification != nil {
+		change.AllowBlindModification = later.AllowBlindModification
+	}
+	if later.DisconnectProtection != nil {
+		change.DisconnectProtection = later.DisconnectProtection
+	}
+	return change
+}
+
+// apply a copy of config with the change made
+func (change ConfigChange) apply(config TableConfig) TableConfig {
+	if change.SmallBlind != nil {
+		config.smallBlind = *change.SmallBlind
+	}
+	if change.BigBlind != nil {
+		config.minBet = *change.BigBlind
+	}
+	if change.Ante != nil {
+		config.ante = *change.Ante
+	}
+	if change.TimeToBet != nil {
+		config.timeToBet = *change.TimeToBet
+	}
+	if change.TimeBetweenHands != nil {
+		config.secondsBetweenHands = *change.TimeBetweenHands
+	}
+	if change.AllowBlindModification != nil {
+		config.allowBlindModification = *change.AllowBlindModification
+	}
+	if change.DisconnectProtection != nil {
+		config.disconnectProtection = *change.DisconnectProtection
+	}
+	return config
+}
+
+// valid if the config can be played with
+func (config TableConfig) valid() error {
+	smallBlind := config.smallBlindAmount()
+	if config.minBet <= 0 || smallBlind <= 0 || smallBlind > config.minBet ||
+		config.ante < 0 {
+		return fmt.Errorf("invalid blinds, sb=%d bb=%d ante=%d",
+			smallBlind, config.minBet, config.ante)
+	} else if config.timeToBet <= 0 {
+		return errors.New("time to bet must be positive")
+	} else if config.secondsBetweenHands < 0 {
+		return errors.New("time between hands cannot be negative")
+	} else if config.minBuyIn < 0 || config.maxBuyIn < 0 {
+		return fmt.Errorf("invalid buy in limits, min=%d max=%d",
+			config.minBuyIn, config.maxBuyIn)
+	} else if config.maxBuyIn > 0 && config.minBuyIn > config.maxBuyIn {
+		return fmt.Errorf("minimum buy in %d is more than the maximum %d",
+			config.minBuyIn, config.maxBuyIn)
+	} else if config.minBuyIn > 0 && config.minBuyIn < config.minBet ||
+		config.maxBuyIn > 0 && config.maxBuyIn < config.minBet {
+		return fmt.Errorf("buy in limits, min=%d max=%d, are less than the "+
+			"big blind %d", config.minBuyIn, config.maxBuyIn, config.minBet)
+	}
+	return config.disconnectProtection.valid()
+}
@@ -0,0 +1,79 @@
+package model
+
+import (
+	"errors"
+	"log"
+	"time"
+)
+
+// DisconnectProtection what happens when a player is disconnected as their
+// clock runs out, the zero value folds them as usual
+type DisconnectProtection struct {
+	// Time the disconnect timer gives the player to reconnect and act
+	Time time.Duration
+	// Protections each player gets per session, none once they are used up
+	Protections int
+	// AllIn treat a player still disconnected once the disconnect timer runs
+	// out as all in for the chips they have already bet rather than folding
+	AllIn bool
+}
+
+// WithDisconnectProtection a copy of the config that protects players who
+// are disconnected on their turn
+func (config TableConfig) WithDisconnectProtection(
+	protection DisconnectProtection) TableConfig {
+	config.disconnectProtection = protection
+	return config
+}
+
+func (protection DisconnectProtection) valid() error {
+	if protection.Time < 0 {
+		return errors.New("disconnect time cannot be negative")
+	} else if protection.Protections < 0 {
+		return errors.New("disconnect protections cannot be negative")
+	}
+	return nil
+}
+
+// SetConnected record whether the player has a connection to the table, a
+// player that is disconnected when their clock runs out may be protected
+func (table *Table) SetConnected(player *Player, connected bool) {
+	table.do(func() error {
+		player.disconnected = !connected
+		return nil
+	})
+}
+
+// protect start the disconnect timer of a player whose clock ran out, if
+// they are disconnected and have a protection left
+func (hand *Hand) protect(player *Player) bool {
+	protection := hand.TableConfig.disconnectProtection
+	if !player.disconnected ||
+		player.protectionsUsed >= protection.Protections {
+		return false
+	}
+	player.protectionsUsed++
+	hand.record("%s is disconnected, %s to reconnect", player.Name,
+		protection.Time)
+	return true
+}
+
+// timedOut the action of a player whose clock ran out, protected if the
+// disconnect timer ran out
+func (hand *Hand) timedOut(player *Player, protected bool) RoundAction {
+	if protected && player.disconnected &&
+		hand.TableConfig.disconnectProtection.AllIn {
+		return RoundAction{actionType: disconnectedAllIn}
+	}
+	return RoundAction{actionType: Fold}
+}
+
+// playerDisconnectedAllIn the player stays in the hand without betting more,
+// they can only win the pots they have already bet into
+func (hand *Hand) playerDisconnectedAllIn(player *Player) {
+	player.AllIn = true
+	if hand.BetterCount() < 1 {
+		hand.BettingDone = true
+		log.Println("Player allin ended betting")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/chehsunliu/poker"
 )
@@ -16,12 +17,17 @@ type (
 		TableConfig TableConfig
 		// Deck of cards
 		Deck poker.Deck
-		// Board shared cards
+		// Board shared cards, the first of Boards if more than one is dealt
 		Board []poker.Card
+		// Boards every board of shared cards when more than one is dealt
+		Boards [][]poker.Card
 		// Round is the current round of betting
-		Round *Round
+		*Round
 		// Players in the hand
 		Players *ring.Ring
+		// DealtIn are all players dealt into the hand, including those that
+		// have since folded
+		DealtIn []*Player
 		// Pot of winnings
 		Pot Pot
 		// FirstToBet bets first
@@ -30,6 +36,32 @@ type (
 		BettingDone bool
 		// If no more dealing is needed for the hand
 		HandDone bool
+		// Result of the hand once it is finished
+		Result *HandResult
+		// History of the hand's events, e.g. "Anna raises to 600"
+		History []string
+		// BombPot every player antes and the hand starts on the flop
+		BombPot bool
+		// startingChips held by the players dealt in before any bets
+		startingChips int
+		// startingStacks of each player dealt in before any bets
+		startingStacks map[*Player]int
+		// stateChanged notifies the table's state listeners
+		stateChanged func(turn *Turn)
+		// pause of the table, stops the clock of the player deciding
+		pause *pauseState
+		// commands queued for the table, made while waiting for players
+		commands chan *command
+		// clock of the player whose turn it is
+		clock turnClock
+	}
+
+	// turnClock the time a player has left to act, running since started
+	// unless play is paused
+	turnClock struct {
+		player    *Player
+		remaining time.Duration
+		started   time.Time
 	}
 
 	// Round is a cycle of betting, there are 4 in a hand: pre-flop, flop, turn, river
@@ -40,9 +72,14 @@ type (
 		CurrentBet int
 		// If the round of betting is done
 		RoundDone bool
+		// foldedBets made this round by players that have since folded, they
+		// are moved into the pots with the rest of the round's bets
+		foldedBets []int
 	}
 )
 
+var streetNames = map[int]string{3: "FLOP", 4: "TURN", 5: "RIVER"}
+
 // NewHand create a hand
 func (table *Table) NewHand() *Hand {
 	if table.Players[table.DealerIndex] == nil {
@@ -52,8 +89,14 @@ func (table *Table) NewHand() *Hand {
 	return &Hand{
 		Deck:        poker.Deck{},
 		TableConfig: table.TableConfig,
+		Round:       &Round{BetTurn: players},
 		Players:     players,
 		Pot:         pot,
+
+		startingStacks: make(map[*Player]int),
+		stateChanged:   table.notifyState,
+		pause:          &table.pause,
+		commands:       table.commands,
 	}
 }
 
@@ -62,7 +105,7 @@ func pRing(ring *ring.Ring) *Player {
 }
 
 func (hand *Hand) validateBlinds() error {
-	lbValid := hand.SmallBlind().Funds >= hand.TableConfig.minBet/2
+	lbValid := hand.SmallBlind().Funds >= hand.TableConfig.smallBlindAmount()
 	bbValid := hand.BigBlind().Funds >= hand.TableConfig.minBet
 	if !lbValid || !bbValid {
 		errStr := "failed to validate blinds, lbFunds=%d bbFunds=%d minBet=%d"
@@ -77,7 +120,8 @@ func (hand *Hand) StartHand() error {
 	if hand.Players.Len() < MinPlayersToPlay {
 		return errors.New("starthand: insufficient players to start hand")
 	}
-	if err := hand.validateBlinds(); err != nil {
+	err := hand.validateBlinds()
+	if err != nil && !hand.TableConfig.shortStackBlinds && !hand.BombPot {
 		return fmt.Errorf("starthand: %w", err)
 	}
 	hand.HandDone = false
@@ -85,13 +129,42 @@ func (hand *Hand) StartHand() error {
 	player := hand.Players
 	for i := 0; i < hand.Players.Len(); i++ {
 		pRing(player).Playing = true
+		pRing(player).AllIn = false
+		hand.DealtIn = append(hand.DealtIn, pRing(player))
+		hand.startingStacks[pRing(player)] = pRing(player).Funds
+		hand.startingChips += pRing(player).Funds + pRing(player).BetAmount
 		hand.dealHole(pRing(player))
 		player = player.Next()
 	}
+	if hand.BombPot {
+		hand.takeBombPot()
+	}
 	hand.startBets()
+	if hand.BombPot && hand.BetterCount() < 2 {
+		hand.BettingDone = true
+	}
+	if err := hand.audit("starthand"); err != nil {
+		return fmt.Errorf("starthand: %w", err)
+	}
 	return nil
 }
 
+// timeToAct the time the player has left to act on their turn
+func (hand *Hand) timeToAct(player *Player) time.Duration {
+	clock := hand.clock
+	if clock.player != player {
+		return hand.TableConfig.timeToBet
+	}
+	remaining := clock.remaining
+	if !clock.started.IsZero() {
+		remaining -= time.Since(clock.started)
+	}
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
 // Dealer is the dealer of the hand
 func (hand *Hand) Dealer() *Player {
 	return pRing(hand.Players)
@@ -107,17 +180,45 @@ func (hand *Hand) BigBlind() *Player {
 	return pRing(hand.Players.Next().Next())
 }
 
-func (hand *Hand) takeBlinds() {
-	hand.SmallBlind().Funds -= hand.TableConfig.minBet / 2
-	hand.SmallBlind().BetAmount = hand.TableConfig.minBet / 2
-	hand.BigBlind().Funds -= hand.TableConfig.minBet
-	hand.BigBlind().BetAmount = hand.TableConfig.minBet
-	if hand.SmallBlind().Funds == 0 {
-		hand.SmallBlind().AllIn = true
+// postBlind the player bets amount, or goes all in if they cannot cover it
+func (hand *Hand) postBlind(player *Player, amount int) {
+	if player.Funds < amount {
+		amount = player.Funds
 	}
-	if hand.BigBlind().Funds == 0 {
-		hand.BigBlind().AllIn = true
+	player.Funds -= amount
+	player.BetAmount = amount
+	if player.Funds == 0 {
+		player.AllIn = true
+	}
+}
+
+// takeAntes every player posts the ante before the blinds, see postAntes
+func (hand *Hand) takeAntes() {
+	if ante := hand.TableConfig.ante; ante > 0 {
+		hand.postAntes(ante, "ante")
 	}
+}
+
+// postAntes every player bets ante, or all they have, and the bets are moved
+// into the pots like a round's, so a player all in for less than the ante
+// can only win the antes they matched
+func (hand *Hand) postAntes(ante int, name string) {
+	hand.Players.Do(func(p interface{}) {
+		player := p.(*Player)
+		hand.postBlind(player, ante)
+		hand.record("%s posts %s %d", player.Name, name, player.BetAmount)
+	})
+	hand.createPots()
+}
+
+func (hand *Hand) takeBlinds() {
+	hand.takeAntes()
+	hand.postBlind(hand.SmallBlind(), hand.TableConfig.smallBlindAmount())
+	hand.postBlind(hand.BigBlind(), hand.TableConfig.minBet)
+	hand.record("%s posts small blind %d", hand.SmallBlind().Name,
+		hand.SmallBlind().BetAmount)
+	hand.record("%s posts big blind %d", hand.BigBlind().Name,
+		hand.BigBlind().BetAmount)
 	if (hand.SmallBlind().AllIn || hand.BigBlind().AllIn) &&
 		hand.Players.Len() == 2 {
 		hand.Round.RoundDone = true
@@ -132,8 +233,8 @@ func (hand *Hand) startBets() {
 	}
 	log.Println("board length", len(hand.Board))
 	if len(hand.Board) == 0 {
-		hand.Round.CurrentBet = hand.TableConfig.minBet
 		hand.takeBlinds()
+		hand.Round.CurrentBet = hand.TableConfig.minBet
 		hand.Round.BetTurn = hand.Players.Next().Next()
 	} else {
 		hand.Round.CurrentBet = 0
@@ -176,6 +277,10 @@ func (hand *Hand) PlayerAction(
 		return errors.New("it's not your turn to bet")
 	}
 	var err error
+	previousBet := player.BetAmount
+	if action.actionType == Bet {
+		action.actionType = hand.betType(player, action.bet)
+	}
 	switch action.actionType {
 	case Call:
 		err = hand.playerBet(player, hand.Round.CurrentBet)
@@ -189,14 +294,53 @@ func (hand *Hand) PlayerAction(
 		err = hand.playerBet(player, action.bet)
 	case Fold:
 		hand.playerFold()
+	case disconnectedAllIn:
+		hand.playerDisconnectedAllIn(player)
 	}
 	if err != nil {
 		return err
 	}
+	hand.recordAction(player, action.actionType, player.BetAmount-previousBet)
 	hand.nextBetter()
 	return nil
 }
 
+// betType the action a total bet for the round amounts to
+func (hand *Hand) betType(player *Player, bet int) ActionType {
+	switch {
+	case bet == player.Funds+player.BetAmount:
+		return AllIn
+	case bet == hand.Round.CurrentBet:
+		return Call
+	}
+	return Raise
+}
+
+func (hand *Hand) recordAction(player *Player, actionType ActionType, added int) {
+	switch {
+	case actionType == Fold:
+		hand.record("%s folds", player.Name)
+	case actionType == disconnectedAllIn:
+		hand.record("%s is disconnected, all in for %d", player.Name,
+			player.BetAmount)
+	case player.AllIn:
+		hand.record("%s is all in for %d", player.Name, player.BetAmount)
+	case actionType == Raise:
+		hand.record("%s raises to %d", player.Name, player.BetAmount)
+	case added == 0:
+		hand.record("%s checks", player.Name)
+	default:
+		hand.record("%s calls %d", player.Name, added)
+	}
+}
+
+// record adds an event to the hand's history
+func (hand *Hand) record(format string, args ...interface{}) {
+	event := fmt.Sprintf(format, args...)
+	log.Println(event)
+	hand.History = append(hand.History, event)
+}
+
 func (hand *Hand) nextBetter() {
 	if hand.Round.RoundDone {
 		log.Println("Skipping nextbetter because round is done")
@@ -221,7 +365,7 @@ func (hand *Hand) nextBetter() {
 func (hand *Hand) playerFold() {
 	player := pRing(hand.Round.BetTurn)
 	player.Hole = []poker.Card{}
-	hand.Pot.MainPot.Pot += player.BetAmount
+	hand.Round.foldedBets = append(hand.Round.foldedBets, player.BetAmount)
 	player.BetAmount = 0
 	for _, pot := range append(hand.Pot.SidePots, hand.Pot.MainPot) {
 		delete(pot.Players, player)
@@ -265,12 +409,47 @@ func (hand *Hand) Deal() error {
 	if len(hand.Board) >= 3 {
 		cardsToDraw = 1
 	}
-	hand.Board = append(hand.Board, hand.Deck.Draw(cardsToDraw)...)
+	hand.dealBoards(cardsToDraw)
 	hand.Round.RoundDone = false
 	hand.startBets()
 	return nil
 }
 
+// boardCount the number of boards dealt in the hand
+func (hand *Hand) boardCount() int {
+	if hand.TableConfig.boards > 1 {
+		return hand.TableConfig.boards
+	} else if hand.BombPot && hand.TableConfig.bombPots.DoubleBoard {
+		return 2
+	}
+	return 1
+}
+
+// dealBoards draw count cards onto every board
+func (hand *Hand) dealBoards(count int) {
+	if hand.boardCount() == 1 {
+		hand.Board = append(hand.Board, hand.Deck.Draw(count)...)
+		hand.record("*** %s *** %v", streetNames[len(hand.Board)], hand.Board)
+		return
+	}
+	if len(hand.Boards) == 0 {
+		hand.Boards = make([][]poker.Card, hand.boardCount())
+	}
+	for i := range hand.Boards {
+		hand.Boards[i] = append(hand.Boards[i], hand.Deck.Draw(count)...)
+	}
+	hand.Board = hand.Boards[0]
+	hand.record("*** %s *** %v", streetNames[len(hand.Board)], hand.Boards)
+}
+
+// boards every board dealt in the hand
+func (hand *Hand) boards() [][]poker.Card {
+	if len(hand.Boards) > 0 {
+		return hand.Boards
+	}
+	return [][]poker.Card{hand.Board}
+}
+
 func (hand *Hand) dealHole(player *Player) {
 	player.Hole = hand.Deck.Draw(2)
 }
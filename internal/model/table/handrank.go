@@ -0,0 +1,115 @@
+package model
+
+import (
+	"sort"
+
+	"github.com/chehsunliu/poker"
+)
+
+var (
+	rankNames = [...]string{"Two", "Three", "Four", "Five", "Six", "Seven",
+		"Eight", "Nine", "Ten", "Jack", "Queen", "King", "Ace"}
+	rankPluralNames = [...]string{"Twos", "Threes", "Fours", "Fives", "Sixes",
+		"Sevens", "Eights", "Nines", "Tens", "Jacks", "Queens", "Kings", "Aces"}
+)
+
+const (
+	straightFlushClass = int32(iota + 1)
+	fourOfAKindClass
+	fullHouseClass
+	flushClass
+	straightClass
+	threeOfAKindClass
+	twoPairClass
+	pairClass
+	highCardClass
+)
+
+// bestHand finds the best five cards out of the board and hole, returning nil
+// if there are not enough cards to make a hand
+func bestHand(board []poker.Card, hole []poker.Card) ([]poker.Card, int32) {
+	cards := make([]poker.Card, 0, len(board)+len(hole))
+	cards = append(append(cards, board...), hole...)
+	if len(cards) < 5 {
+		return nil, 0
+	}
+	var best []poker.Card
+	var bestRank int32
+	combination := make([]poker.Card, 5)
+	var choose func(start, depth int)
+	choose = func(start, depth int) {
+		if depth == 5 {
+			rank := poker.Evaluate(combination)
+			if best == nil || rank < bestRank {
+				best = append([]poker.Card{}, combination...)
+				bestRank = rank
+			}
+			return
+		}
+		for i := start; i <= len(cards)-(5-depth); i++ {
+			combination[depth] = cards[i]
+			choose(i+1, depth+1)
+		}
+	}
+	choose(0, 0)
+	return best, bestRank
+}
+
+// rankGroups the card ranks of the hand ordered by how many times they appear
+// and then by rank, e.g. a full house of kings and sevens returns [K, 7]
+func rankGroups(cards []poker.Card) []int32 {
+	counts := map[int32]int{}
+	for _, c := range cards {
+		counts[c.Rank()]++
+	}
+	ranks := []int32{}
+	for r := range counts {
+		ranks = append(ranks, r)
+	}
+	sort.Slice(ranks, func(i, j int) bool {
+		if counts[ranks[i]] != counts[ranks[j]] {
+			return counts[ranks[i]] > counts[ranks[j]]
+		}
+		return ranks[i] > ranks[j]
+	})
+	return ranks
+}
+
+// straightHigh the high card of a straight, accounting for the five high wheel
+func straightHigh(ranks []int32) int32 {
+	if len(ranks) == 5 && ranks[0] == 12 && ranks[1] == 3 {
+		return 3
+	}
+	return ranks[0]
+}
+
+// describeHand a human readable description of five cards, e.g.
+// "Full House, Kings full of Sevens"
+func describeHand(cards []poker.Card, rank int32) string {
+	ranks := rankGroups(cards)
+	switch poker.RankClass(rank) {
+	case straightFlushClass:
+		if straightHigh(ranks) == 12 {
+			return "Royal Flush"
+		}
+		return "Straight Flush, " + rankNames[straightHigh(ranks)] + " high"
+	case fourOfAKindClass:
+		return "Four of a Kind, " + rankPluralNames[ranks[0]]
+	case fullHouseClass:
+		return "Full House, " + rankPluralNames[ranks[0]] + " full of " +
+			rankPluralNames[ranks[1]]
+	case flushClass:
+		return "Flush, " + rankNames[ranks[0]] + " high"
+	case straightClass:
+		return "Straight, " + rankNames[straightHigh(ranks)] + " high"
+	case threeOfAKindClass:
+		return "Three of a Kind, " + rankPluralNames[ranks[0]]
+	case twoPairClass:
+		return "Two Pair, " + rankPluralNames[ranks[0]] + " and " +
+			rankPluralNames[ranks[1]]
+	case pairClass:
+		return "Pair of " + rankPluralNames[ranks[0]]
+	default:
+		return "High Card, " + rankNames[ranks[0]]
+	}
+}
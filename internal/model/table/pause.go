@@ -0,0 +1,95 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// pauseState whether play at a Table is paused, changed is closed and
+// replaced whenever it is paused or resumed to wake anything waiting on it.
+// Once stopped play cannot be paused, the hand being finished would never be
+// resumed.
+type pauseState struct {
+	paused     bool
+	stopped    bool
+	changed    chan struct{}
+	pauseMutex sync.Mutex
+}
+
+// state whether play is paused and a channel closed when that changes
+func (pause *pauseState) state() (bool, <-chan struct{}) {
+	pause.pauseMutex.Lock()
+	defer pause.pauseMutex.Unlock()
+	if pause.changed == nil {
+		pause.changed = make(chan struct{})
+	}
+	return pause.paused, pause.changed
+}
+
+// set whether play is paused, failing if it already was or wasn't or play
+// is stopped
+func (pause *pauseState) set(paused bool) error {
+	pause.pauseMutex.Lock()
+	defer pause.pauseMutex.Unlock()
+	if paused && pause.stopped {
+		return errors.New("table is stopping")
+	} else if pause.paused == paused && paused {
+		return errors.New("table is already paused")
+	} else if pause.paused == paused {
+		return errors.New("table is not paused")
+	}
+	pause.setLocked(paused)
+	return nil
+}
+
+// stop resume play, if paused, and refuse to pause it again until started
+func (pause *pauseState) stop(stopped bool) {
+	pause.pauseMutex.Lock()
+	defer pause.pauseMutex.Unlock()
+	pause.stopped = stopped
+	if stopped && pause.paused {
+		pause.setLocked(false)
+	}
+}
+
+// setLocked change whether play is paused. Called holding pauseMutex.
+func (pause *pauseState) setLocked(paused bool) {
+	pause.paused = paused
+	if pause.changed != nil {
+		close(pause.changed)
+	}
+	pause.changed = make(chan struct{})
+}
+
+// Playing if a goroutine is playing hands at the table
+func (table *Table) Playing() bool {
+	table.tableMutex.RLock()
+	defer table.tableMutex.RUnlock()
+	return table.playing
+}
+
+// Paused if play at the table is paused
+func (table *Table) Paused() bool {
+	paused, _ := table.pause.state()
+	return paused
+}
+
+// Pause play at the table, no hand is dealt until it is resumed and the
+// clock of a player deciding on their action is stopped until then
+func (table *Table) Pause() error {
+	if !table.Playing() {
+		return errors.New("pause: table is not playing")
+	} else if err := table.pause.set(true); err != nil {
+		return fmt.Errorf("pause: %w", err)
+	}
+	return nil
+}
+
+// Resume play at a paused table
+func (table *Table) Resume() error {
+	if err := table.pause.set(false); err != nil {
+		return fmt.Errorf("resume: %w", err)
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package model
 
 import (
 	"errors"
+	"fmt"
 	"log"
 	"sort"
 
@@ -20,117 +21,402 @@ type (
 		Players map[*Player]struct{}
 		Pot     int
 	}
+
+	// HandResult itemises how the Pot of a finished Hand was awarded
+	HandResult struct {
+		Pots []PotResult
+		// Rake collected from the pots by the house
+		Rake int
+		// Net change in the stack of each player dealt into the hand
+		Net map[*Player]int
+		// Eliminations of the players that lost all of their chips
+		Eliminations []Elimination
+		// Boards the pots were split between, one unless more were dealt
+		Boards [][]poker.Card
+	}
+
+	// Elimination is a player that lost all of their chips in a Hand
+	Elimination struct {
+		Player *Player
+		// By the winners of the last pot the player was eligible for, more
+		// than one if it was split
+		By []*Player
+	}
+
+	// PotResult is the outcome of a single SubPot
+	PotResult struct {
+		// Amount in the SubPot before it was awarded
+		Amount int
+		// Rake taken from the Amount before it was awarded
+		Rake int
+		// Players that were eligible to win the SubPot
+		Players []*Player
+		// Winners that split the SubPot, usually only one
+		Winners []PotWinner
+		// Uncontested if only one player was eligible, no hand is shown
+		Uncontested bool
+	}
+
+	// PotWinner is a player's share of a SubPot and the hand that won it
+	PotWinner struct {
+		Player *Player
+		Amount int
+		// BestHand the best five cards, empty if the pot was uncontested
+		BestHand []poker.Card
+		// Description e.g. "Full House, Kings full of Sevens"
+		Description string
+		// Board the index of the board the share was won on
+		Board int
+	}
 )
 
-func (hand *Hand) createPots() {
-	if hand.CurrentBet == 0 {
+// subPots the side pots followed by the main pot
+func (pot *Pot) subPots() []*SubPot {
+	pots := []*SubPot{}
+	for i := range pot.SidePots {
+		pots = append(pots, &pot.SidePots[i])
+	}
+	return append(pots, &pot.MainPot)
+}
+
+// Total the chips in all of the sub pots
+func (pot *Pot) Total() int {
+	total := 0
+	for _, subPot := range pot.subPots() {
+		total += subPot.Pot
+	}
+	return total
+}
+
+// returnUncalledBet gives the last aggressor back the part of their bet that
+// no one else could match
+func (hand *Hand) returnUncalledBet() {
+	var top *Player
+	topBet, secondBet := 0, 0
+	for _, bet := range hand.Round.foldedBets {
+		if bet > secondBet {
+			secondBet = bet
+		}
+	}
+	hand.Players.Do(func(p interface{}) {
+		player := p.(*Player)
+		if player.BetAmount > topBet {
+			if topBet > secondBet {
+				secondBet = topBet
+			}
+			top, topBet = player, player.BetAmount
+		} else if player.BetAmount > secondBet {
+			secondBet = player.BetAmount
+		}
+	})
+	if top == nil || topBet <= secondBet {
 		return
 	}
-	playersAscBet := []*Player{}
+	uncalled := topBet - secondBet
+	top.BetAmount -= uncalled
+	top.Funds += uncalled
+	top.AllIn = false
+	hand.record("Uncalled bet (%d) returned to %s", uncalled, top.Name)
+}
+
+// createPots moves the round's bets into the pots, closing the main pot off as
+// a side pot at each level a player went all in
+func (hand *Hand) createPots() {
+	hand.returnUncalledBet()
+	maxBet := 0
+	levels := []int{}
 	hand.Players.Do(func(p interface{}) {
-		if p.(*Player).BetAmount > 0 {
-			playersAscBet = append(playersAscBet, p.(*Player))
+		player := p.(*Player)
+		if player.BetAmount > maxBet {
+			maxBet = player.BetAmount
 		}
 	})
-	sort.Slice(playersAscBet, func(i, j int) bool {
-		return playersAscBet[i].BetAmount < playersAscBet[j].BetAmount
+	hand.Players.Do(func(p interface{}) {
+		player := p.(*Player)
+		_, inMainPot := hand.Pot.MainPot.Players[player]
+		if player.AllIn && inMainPot && player.BetAmount < maxBet {
+			levels = append(levels, player.BetAmount)
+		}
 	})
-	bet := hand.CurrentBet
-	for i, player := range playersAscBet {
-		if player.BetAmount < bet {
-			hand.createSidePot(player, playersAscBet, i)
-			bet = hand.CurrentBet - player.BetAmount
-		} else {
-			hand.Pot.MainPot.Pot += player.BetAmount
-			hand.Pot.MainPot.Players[player] = struct{}{}
+	sort.Ints(levels)
+	levels = append(levels, maxBet)
+	bets := map[*Player]int{}
+	hand.Players.Do(func(p interface{}) {
+		bets[p.(*Player)] = p.(*Player).BetAmount
+	})
+	allBets := append(betValues(bets), hand.Round.foldedBets...)
+	previous := 0
+	for i, level := range levels {
+		if i > 0 && level == previous {
+			continue
+		}
+		for _, bet := range allBets {
+			if bet > level {
+				hand.Pot.MainPot.Pot += level - previous
+			} else if bet > previous {
+				hand.Pot.MainPot.Pot += bet - previous
+			}
 		}
-		player.BetAmount = 0
+		if i < len(levels)-1 {
+			hand.closeMainPot(bets, level)
+		}
+		previous = level
 	}
+	hand.Players.Do(func(p interface{}) {
+		p.(*Player).BetAmount = 0
+	})
+	hand.Round.foldedBets = nil
 	hand.CurrentBet = 0
 }
 
-func (hand *Hand) createSidePot(player *Player, playersAscBet []*Player, i int) {
-	// Take bet amt out of everyone's funds, add to mainpot, move mainpot
-	// to a sidepot, and create a new mainpot
-	hand.Pot.MainPot.Pot += player.BetAmount
-	hand.Pot.MainPot.Players[player] = struct{}{}
-	for _, p := range playersAscBet[i+1:] {
-		p.BetAmount -= player.BetAmount
-		hand.Pot.MainPot.Players[p] = struct{}{}
-		hand.Pot.MainPot.Pot += player.BetAmount
+// closeMainPot moves the main pot to the side pots, the new main pot can only
+// be won by players that bet more than level
+func (hand *Hand) closeMainPot(bets map[*Player]int, level int) {
+	mainPot := SubPot{make(map[*Player]struct{}), 0}
+	for p := range hand.Pot.MainPot.Players {
+		if bets[p] > level {
+			mainPot.Players[p] = struct{}{}
+		}
+	}
+	if hand.Pot.MainPot.Pot > 0 {
+		hand.Pot.SidePots = append(hand.Pot.SidePots, hand.Pot.MainPot)
 	}
-	hand.Pot.SidePots = append(hand.Pot.SidePots, hand.Pot.MainPot)
-	hand.Pot.MainPot = SubPot{make(map[*Player]struct{}), 0}
+	hand.Pot.MainPot = mainPot
+}
+
+func betValues(bets map[*Player]int) []int {
+	values := []int{}
+	for _, bet := range bets {
+		values = append(values, bet)
+	}
+	return values
 }
 
 // FinishHand is called when all betting is complete and the pot should be
-// distributed.
-func (hand *Hand) FinishHand() error {
+// distributed. The returned HandResult itemises how each SubPot was awarded,
+// an error alongside a result means the hand failed a strict chip audit.
+func (hand *Hand) FinishHand() (*HandResult, error) {
 	if !hand.RoundDone || !hand.HandDone {
-		return errors.New("finishhand: table is currently betting")
+		return nil, errors.New("finishhand: table is currently betting")
 	}
 	log.Println("Distributing pots")
-	playerRanking := hand.getPlayerRanking()
-	hand.distributePots(playerRanking)
+	hand.Players.Do(func(p interface{}) {
+		player := p.(*Player)
+		player.BestHand, player.HandRank = bestHand(hand.Board, player.Hole)
+	})
+	hand.Result = hand.distributePots()
+	hand.Result.Boards = hand.boards()
+	hand.Result.Net = make(map[*Player]int)
+	for _, p := range hand.DealtIn {
+		hand.Result.Net[p] = p.Funds - hand.startingStacks[p]
+	}
+	hand.Result.Eliminations = hand.eliminations()
 	// Clear player holes
 	hand.Players.Do(func(p interface{}) {
 		p.(*Player).Hole = []poker.Card{}
 	})
-	// Clear board
+	// Clear boards
 	hand.Board = []poker.Card{}
-	return nil
+	hand.Boards = nil
+	if err := hand.audit("finishhand"); err != nil {
+		return hand.Result, err
+	}
+	return hand.Result, nil
 }
 
-func (hand *Hand) getPlayerRanking() [][]*Player {
+// getPlayerRanking the players in the hand grouped by the strength of their
+// hand on board, strongest first
+func (hand *Hand) getPlayerRanking(board []poker.Card) [][]*Player {
 	var pRank []*Player
-	if hand.Players.Len() == 1 {
-		return [][]*Player{{pRing(hand.Players)}}
-	}
+	ranks := make(map[*Player]int32)
 	hand.Players.Do(func(p interface{}) {
 		player := p.(*Player)
-		player.HandRank = poker.Evaluate(append(hand.Board, player.Hole...))
+		_, ranks[player] = bestHand(board, player.Hole)
 		pRank = append(pRank, player)
 	})
-	sort.Slice(pRank, func(p1 int, p2 int) bool {
-		return pRank[p1].HandRank < pRank[p2].HandRank
+	if len(pRank) == 1 {
+		return [][]*Player{pRank}
+	}
+	sort.SliceStable(pRank, func(p1 int, p2 int) bool {
+		return ranks[pRank[p1]] < ranks[pRank[p2]]
 	})
 	playerRanking := [][]*Player{}
 	playerRanking = append(playerRanking, []*Player{pRank[0]})
-	rating := pRank[0].HandRank
+	rating := ranks[pRank[0]]
 	rank := 0
 	for _, p := range pRank[1:] {
-		if rating == p.HandRank {
+		if rating == ranks[p] {
 			playerRanking[rank] = append(playerRanking[rank], p)
 		} else {
 			rank++
 			playerRanking = append(playerRanking, []*Player{p})
 		}
-		rating = p.HandRank
+		rating = ranks[p]
 	}
 	return playerRanking
 }
 
-func (hand *Hand) distributePots(playerRanking [][]*Player) {
-	for _, pot := range append(hand.Pot.SidePots, hand.Pot.MainPot) {
-		for _, pRanking := range playerRanking {
-			winners := []*Player{}
-			for _, p := range pRanking {
-				if _, ok := pot.Players[p]; ok {
-					winners = append(winners, p)
-				}
+// eligiblePlayers the players in the hand that can win the pot, in seat order
+// starting from the dealer
+func (hand *Hand) eligiblePlayers(pot SubPot) []*Player {
+	eligible := []*Player{}
+	hand.Players.Do(func(p interface{}) {
+		if _, ok := pot.Players[p.(*Player)]; ok {
+			eligible = append(eligible, p.(*Player))
+		}
+	})
+	return eligible
+}
+
+// distributePots award each pot, split evenly between the boards with any odd
+// chip going to the first, to the best hands on each board
+func (hand *Hand) distributePots() *HandResult {
+	result := &HandResult{}
+	boards := hand.boards()
+	rankings := make([][][]*Player, len(boards))
+	for i, board := range boards {
+		rankings[i] = hand.getPlayerRanking(board)
+	}
+	rakeRemaining := hand.rakeLimit()
+	for _, pot := range hand.Pot.subPots() {
+		if pot.Pot == 0 {
+			continue
+		}
+		potResult := PotResult{Amount: pot.Pot, Players: hand.eligiblePlayers(*pot)}
+		potResult.Uncontested = len(potResult.Players) == 1
+		potResult.Rake = hand.potRake(pot.Pot, rakeRemaining)
+		rakeRemaining -= potResult.Rake
+		result.Rake += potResult.Rake
+		pot.Pot -= potResult.Rake
+		shares := splitAmount(pot.Pot, len(boards))
+		if potResult.Uncontested {
+			shares = []int{pot.Pot}
+		}
+		for i, share := range shares {
+			winners := hand.potWinners(*pot, rankings[i], share, boards[i], i,
+				potResult.Uncontested)
+			potResult.Winners = append(potResult.Winners, winners...)
+			pot.Pot -= share
+		}
+		result.Pots = append(result.Pots, potResult)
+	}
+	return result
+}
+
+// potWinners award amount of the pot to the best ranked players eligible for
+// it, splitting it evenly with any odd chips going to the last
+func (hand *Hand) potWinners(pot SubPot, playerRanking [][]*Player, amount int,
+	board []poker.Card, boardIndex int, uncontested bool) []PotWinner {
+	potWinners := []PotWinner{}
+	for _, pRanking := range playerRanking {
+		winners := []*Player{}
+		for _, p := range pRanking {
+			if _, ok := pot.Players[p]; ok {
+				winners = append(winners, p)
 			}
-			if len(winners) > 0 {
-				minWinnings := pot.Pot / len(winners)
-				for i, p := range winners {
-					if i == len(winners)-1 {
-						p.Funds += pot.Pot
-					} else {
-						p.Funds += minWinnings
-						pot.Pot -= minWinnings
-					}
+		}
+		if len(winners) == 0 {
+			continue
+		}
+		minWinnings := amount / len(winners)
+		for i, p := range winners {
+			winnings := minWinnings
+			if i == len(winners)-1 {
+				winnings = amount
+			}
+			p.Funds += winnings
+			amount -= winnings
+			winner := hand.potWinner(p, winnings, uncontested, board)
+			winner.Board = boardIndex
+			potWinners = append(potWinners, winner)
+			on := ""
+			if len(hand.Boards) > 1 {
+				on = fmt.Sprintf(" on board %d", boardIndex+1)
+			}
+			if winner.Description != "" {
+				hand.record("%s wins %d with %s%s", p.Name, winnings,
+					winner.Description, on)
+			} else {
+				hand.record("%s wins %d%s", p.Name, winnings, on)
+			}
+		}
+		break
+	}
+	return potWinners
+}
+
+func (hand *Hand) potWinner(player *Player, amount int, uncontested bool,
+	board []poker.Card) PotWinner {
+	winner := PotWinner{Player: player, Amount: amount}
+	if uncontested {
+		return winner
+	}
+	if best, rank := bestHand(board, player.Hole); len(best) > 0 {
+		winner.BestHand = best
+		winner.Description = describeHand(best, rank)
+	}
+	return winner
+}
+
+// splitAmount into parts that differ by at most one, the first get the extra
+func splitAmount(amount int, parts int) []int {
+	shares := make([]int, parts)
+	for i := range shares {
+		shares[i] = amount / parts
+		if i < amount%parts {
+			shares[i]++
+		}
+	}
+	return shares
+}
+
+// eliminations the players dealt in that have no chips left, eliminated by
+// the winners of the pot at the level they were all in for, i.e. the last
+// pot they were eligible for
+func (hand *Hand) eliminations() []Elimination {
+	eliminations := []Elimination{}
+	for _, p := range hand.DealtIn {
+		if p.Funds > 0 || p.pendingChips > 0 {
+			continue
+		}
+		elimination := Elimination{Player: p}
+		for _, pot := range hand.Result.Pots {
+			for _, eligible := range pot.Players {
+				if eligible == p {
+					elimination.By = winnersOf(pot)
 				}
-				break
+			}
+		}
+		eliminations = append(eliminations, elimination)
+	}
+	return eliminations
+}
+
+// winnersOf the players that won a share of the pot, once each even if they
+// won on more than one board
+func winnersOf(pot PotResult) []*Player {
+	winners := []*Player{}
+	for _, w := range pot.Winners {
+		found := false
+		for _, p := range winners {
+			found = found || p == w.Player
+		}
+		if !found {
+			winners = append(winners, w.Player)
+		}
+	}
+	return winners
+}
+
+// Winnings the total amount awarded to the player across all pots
+func (result *HandResult) Winnings(player *Player) int {
+	winnings := 0
+	for _, pot := range result.Pots {
+		for _, w := range pot.Winners {
+			if w.Player == player {
+				winnings += w.Amount
 			}
 		}
 	}
+	return winnings
 }
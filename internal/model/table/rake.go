@@ -0,0 +1,44 @@
+package model
+
+import "math"
+
+type (
+	// RakeConfig defines how much of each hand's pot is collected by the house
+	RakeConfig struct {
+		// Percent of each pot that is raked, e.g. 5 for 5%
+		Percent float64
+		// Cap is the most rake that can be taken from a hand, zero for no cap
+		Cap int
+		// PlayerCaps overrides Cap by the number of players dealt into the hand
+		PlayerCaps map[int]int
+		// NoFlopNoDrop takes no rake when the hand ends before the flop
+		NoFlopNoDrop bool
+		// MinPot is the total pot below which no rake is taken
+		MinPot int
+	}
+)
+
+// rakeLimit the most rake that can be taken from the hand's pots
+func (hand *Hand) rakeLimit() int {
+	rake := hand.TableConfig.rake
+	total := hand.Pot.Total()
+	if rake.Percent <= 0 || total < rake.MinPot ||
+		rake.NoFlopNoDrop && len(hand.Board) == 0 {
+		return 0
+	}
+	if playerCap, ok := rake.PlayerCaps[len(hand.DealtIn)]; ok {
+		return playerCap
+	} else if rake.Cap > 0 {
+		return rake.Cap
+	}
+	return total
+}
+
+// potRake the rake to take from a pot without exceeding the remaining limit
+func (hand *Hand) potRake(pot int, remaining int) int {
+	rake := int(math.Floor(float64(pot) * hand.TableConfig.rake.Percent / 100))
+	if rake > remaining {
+		return remaining
+	}
+	return rake
+}
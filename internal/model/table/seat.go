@@ -0,0 +1,141 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+)
+
+const (
+	// AnySeat sit at the first free seat
+	AnySeat = -1
+	// RandomSeat sit at a free seat chosen at random
+	RandomSeat = -2
+)
+
+// seatChange a seated player's request to move to another seat once it is
+// free
+type seatChange struct {
+	player *Player
+	seat   int
+}
+
+// chooseSeat the seat to sit at for a seat, AnySeat or RandomSeat, which are
+// only chosen from free seats not offered or requested by other players
+func (table *Table) chooseSeat(seat int) (int, error) {
+	if seat != AnySeat && seat != RandomSeat {
+		return seat, nil
+	}
+	var free []int
+	for i, p := range table.Players {
+		if p == nil && table.seatOfferedTo(i) == nil &&
+			table.seatRequestedBy(i) == nil {
+			free = append(free, i)
+		}
+	}
+	if len(free) == 0 {
+		return 0, errors.New("Table has no free seat")
+	} else if seat == RandomSeat {
+		return free[rand.Intn(len(free))], nil
+	}
+	return free[0], nil
+}
+
+// RequestSeatChange move the player to seat once it is free and no hand is
+// being played, before any later requests for it. A player that changes seats
+// sits out until the big blind reaches them so they cannot skip it.
+func (table *Table) RequestSeatChange(player *Player, seat int) error {
+	return table.do(func() error {
+		current := table.seatOf(player)
+		if current < 0 {
+			return errors.New(
+				"requestseatchange: player is not sitting at this table")
+		} else if seat < 0 || seat >= MaxTableSize {
+			return fmt.Errorf("requestseatchange: seat %d is outside the table",
+				seat)
+		} else if seat == current {
+			return errors.New("requestseatchange: player is already at the seat")
+		}
+		table.cancelSeatChange(player)
+		table.seatChanges = append(table.seatChanges,
+			seatChange{player: player, seat: seat})
+		if table.Hand == nil || table.Hand.HandDone {
+			table.applySeatChanges()
+		}
+		return nil
+	})
+}
+
+// CancelSeatChange withdraw the player's request to change seats
+func (table *Table) CancelSeatChange(player *Player) error {
+	return table.do(func() error {
+		if !table.cancelSeatChange(player) {
+			return errors.New("cancelseatchange: player has not requested a " +
+				"seat change")
+		}
+		table.offerSeats()
+		return nil
+	})
+}
+
+func (table *Table) cancelSeatChange(player *Player) bool {
+	cancelled := false
+	changes := table.seatChanges[:0]
+	for _, change := range table.seatChanges {
+		if change.player == player {
+			cancelled = true
+		} else {
+			changes = append(changes, change)
+		}
+	}
+	table.seatChanges = changes
+	return cancelled
+}
+
+// applySeatChanges move players whose requested seat has become free, in the
+// order they asked, then offer the seats they left to the waitlist. Requests
+// of players no longer seated are dropped. Called holding tableMutex between
+// hands.
+func (table *Table) applySeatChanges() {
+	for moved := true; moved; {
+		moved = false
+		for _, change := range table.seatChanges {
+			current := table.seatOf(change.player)
+			if current < 0 {
+				table.cancelSeatChange(change.player)
+				moved = true
+				break
+			} else if table.Players[change.seat] != nil {
+				continue
+			}
+			table.Players[current] = nil
+			table.Players[change.seat] = change.player
+			change.player.waitForBigBlind = true
+			table.cancelSeatChange(change.player)
+			moved = true
+			break
+		}
+	}
+	table.offerSeats()
+}
+
+// seatRequestedBy the first player to request a change to seat, nil if
+// nobody has
+func (table *Table) seatRequestedBy(seat int) *Player {
+	for _, change := range table.seatChanges {
+		if change.seat == seat {
+			return change.player
+		}
+	}
+	return nil
+}
+
+// seatOf the player's seat, -1 if they are not seated
+func (table *Table) seatOf(player *Player) int {
+	for i, p := range table.Players {
+		if p == player {
+			return i
+		}
+	}
+	return -1
+}
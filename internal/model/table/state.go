@@ -0,0 +1,201 @@
+package model
+
+import (
+	"time"
+
+	"github.com/chehsunliu/poker"
+)
+
+type (
+	// TableState is a snapshot of a Table as seen by one player, only the
+	// viewer's own hole cards are included
+	TableState struct {
+		// Seats of the table, nil if empty
+		Seats [MaxTableSize]*SeatState
+		// DealerSeat the seat with the button
+		DealerSeat int
+		// BetTurnSeat the seat whose turn it is to bet, -1 if nobody's
+		BetTurnSeat int
+		// TimeToAct the time left on the clock of the player whose turn it is,
+		// it keeps running while they are disconnected
+		TimeToAct time.Duration
+		// Board shared cards, the first of Boards if more than one is dealt
+		Board  []string
+		Boards [][]string
+		// Pot the chips in the pots, not including bets of the current round
+		Pot int
+		// CurrentBet the amount to call this round
+		CurrentBet int
+		// History of the current hand's events
+		History []string
+		// Paused if play is paused
+		Paused bool
+		Config ConfigState
+		// Standers the names of the players watching the table
+		Standers []string
+		// Waitlist the names of the players waiting for a seat, in order
+		Waitlist []string
+		// SeatOffers the seats offered to players on the waitlist
+		SeatOffers []SeatOfferState
+		// SeatChanges the seats seated players asked to move to, in order
+		SeatChanges []SeatChangeState
+	}
+
+	// SeatChangeState a seated player's request to move to Seat
+	SeatChangeState struct {
+		Name string
+		Seat int
+	}
+
+	// SeatOfferState a seat offered to a player on the waitlist
+	SeatOfferState struct {
+		Name    string
+		Seat    int
+		Expires time.Time
+	}
+
+	// SeatState is a player sitting at a Table
+	SeatState struct {
+		Name      string
+		Funds     int
+		BetAmount int
+		Playing   bool
+		AllIn     bool
+		// WaitingForBigBlind after changing seats
+		WaitingForBigBlind bool
+		// Disconnected from the table
+		Disconnected bool
+		// Hole cards, only for the viewer
+		Hole []string
+	}
+
+	// ConfigState the parts of a TableConfig players are shown
+	ConfigState struct {
+		SmallBlind int
+		BigBlind   int
+		Ante       int
+		TimeToBet  time.Duration
+		// TimeBetweenHands the pause after each hand
+		TimeBetweenHands time.Duration
+		// AllowBlindModification by players rather than only admins
+		AllowBlindModification bool
+		DisconnectProtection   DisconnectProtection
+	}
+)
+
+// State a snapshot of the table as seen by viewer, who may be nil
+func (table *Table) State(viewer *Player) TableState {
+	var state TableState
+	table.do(func() error {
+		state = table.state(viewer)
+		return nil
+	})
+	return state
+}
+
+func (table *Table) state(viewer *Player) TableState {
+	state := TableState{
+		DealerSeat:  table.DealerIndex,
+		BetTurnSeat: -1,
+		Config:      table.TableConfig.state(),
+	}
+	state.Paused, _ = table.pause.state()
+	for i, p := range table.Players {
+		if p == nil {
+			continue
+		}
+		seat := &SeatState{
+			Name: p.Name, Funds: p.Funds, BetAmount: p.BetAmount,
+			Playing: p.Playing, AllIn: p.AllIn,
+			WaitingForBigBlind: p.waitForBigBlind,
+			Disconnected:       p.disconnected,
+		}
+		if p == viewer {
+			seat.Hole = cardStrings(p.Hole)
+		}
+		state.Seats[i] = seat
+	}
+	for _, p := range table.Standers {
+		if p != nil {
+			state.Standers = append(state.Standers, p.Name)
+		}
+	}
+	for _, p := range table.waitlist.waiting {
+		state.Waitlist = append(state.Waitlist, p.Name)
+	}
+	for _, change := range table.seatChanges {
+		state.SeatChanges = append(state.SeatChanges, SeatChangeState{
+			Name: change.player.Name, Seat: change.seat,
+		})
+	}
+	for _, offer := range table.waitlist.offers {
+		state.SeatOffers = append(state.SeatOffers, SeatOfferState{
+			Name: offer.Player.Name, Seat: offer.Seat, Expires: offer.Expires,
+		})
+	}
+	hand := table.Hand
+	if hand == nil {
+		return state
+	}
+	state.Board = cardStrings(hand.Board)
+	for _, board := range hand.Boards {
+		state.Boards = append(state.Boards, cardStrings(board))
+	}
+	state.Pot = hand.Pot.Total()
+	state.History = append([]string{}, hand.History...)
+	if hand.Round != nil && hand.Round.BetTurn != nil && !hand.Round.RoundDone &&
+		!hand.HandDone {
+		state.CurrentBet = hand.Round.CurrentBet
+		better := pRing(hand.Round.BetTurn)
+		for i, p := range table.Players {
+			if p != nil && p == better {
+				state.BetTurnSeat = i
+				state.TimeToAct = hand.timeToAct(better)
+			}
+		}
+	}
+	return state
+}
+
+// Present if the player named name is seated at or watching the table
+func (state TableState) Present(name string) bool {
+	for _, seat := range state.Seats {
+		if seat != nil && seat.Name == name {
+			return true
+		}
+	}
+	for _, stander := range state.Standers {
+		if stander == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Config the parts of the table's config players are shown
+func (table *Table) Config() ConfigState {
+	table.tableMutex.RLock()
+	defer table.tableMutex.RUnlock()
+	return table.TableConfig.state()
+}
+
+func (config TableConfig) state() ConfigState {
+	return ConfigState{
+		SmallBlind: config.smallBlindAmount(),
+		BigBlind:   config.minBet,
+		Ante:       config.ante,
+		TimeToBet:  config.timeToBet,
+
+		TimeBetweenHands:       config.secondsBetweenHands,
+		AllowBlindModification: config.allowBlindModification,
+		DisconnectProtection:   config.disconnectProtection,
+	}
+}
+
+func cardStrings(cards []poker.Card) []string {
+	out := make([]string, len(cards))
+	for i, c := range cards {
+		out[i] = c.String()
+	}
+	return out
+}
@@ -28,9 +28,19 @@ type (
 		Funds         int
 		BetAmount     int
 		HandRank      int32
+		BestHand      []poker.Card
 		ActionChan    chan RoundAction
 		SignalChan    chan Signal
 		table         *Table
+		// pendingChips bought by a top up that are added between hands
+		pendingChips int
+		// waitForBigBlind after changing seats, the player sits out until
+		// they would post the big blind
+		waitForBigBlind bool
+		// disconnected from the table, according to its websocket
+		disconnected bool
+		// protectionsUsed of the disconnect protections the player gets
+		protectionsUsed int
 	}
 
 	// PlayerBet a bet that is made in a round
@@ -47,14 +57,83 @@ type (
 		playing     bool
 		Standers    [MaxStandersSize]*Player
 		Hand        *Hand
-		tableMutex  sync.RWMutex
+		// Bank players buy in from and cash out to, if nil players sit with
+		// whatever Funds they already have
+		Bank       Bank
+		tableMutex sync.RWMutex
+		// rakeCollected over all hands played at the table
+		rakeCollected int
+		// departures of players that recently left with chips, by name
+		departures map[string]departure
+		// handListeners are called after each hand is finished
+		handListeners []HandListener
+		// stateListeners are called whenever the state of the table changes
+		stateListeners []StateListener
+		// blinds is the table's progress through its blind schedule
+		blinds blindState
+		// bombPot tracks when the next bomb pot is due
+		bombPot bombPotState
+		// pendingConfig changes that are applied before the next hand
+		pendingConfig *ConfigChange
+		// pause stops play between hands and the action clock during them
+		pause pauseState
+		// commands queued for the goroutine playing the table
+		commands chan *command
+		// released is closed when changes are no longer queued, because the
+		// table stopped playing or is calling its listeners
+		released chan struct{}
+		// listening while the goroutine playing the table calls its listeners
+		listening bool
+		// waitlist of standers waiting for a seat
+		waitlist waitlistState
+		// seatChanges requested by seated players, in the order they asked
+		seatChanges []seatChange
+	}
+
+	// HandListener is called by the goroutine playing the Table after each
+	// Hand is finished and before the next is dealt
+	HandListener func(table *Table, result *HandResult)
+
+	// StateListener is called by the goroutine playing the Table whenever its
+	// state changes, turn is the player's action that changed it, if any
+	StateListener func(table *Table, turn *Turn)
+
+	// Turn is the action a player took on their turn in a round
+	Turn struct {
+		Player *Player
+		Fold   bool
+		// Bet the player's total bet for the round after the action
+		Bet int
+	}
+
+	// departure remembers the stack a player left a Table with
+	departure struct {
+		stack int
+		at    time.Time
 	}
 
 	// TableConfig define nuances of the game played at a Table
 	TableConfig struct {
 		minBet              int
+		smallBlind          int
+		ante                int
 		timeToBet           time.Duration
 		secondsBetweenHands time.Duration
+		rake                RakeConfig
+		audit               AuditMode
+		minBuyIn            int
+		maxBuyIn            int
+		ratholeWindow       time.Duration
+		blindLevels         []BlindLevel
+		shortStackBlinds    bool
+		bombPots            BombPotConfig
+		boards              int
+		// allowBlindModification by players rather than only admins
+		allowBlindModification bool
+		// seatOfferTimeout to accept a seat offered from the waitlist
+		seatOfferTimeout time.Duration
+		// disconnectProtection of players disconnected on their turn
+		disconnectProtection DisconnectProtection
 	}
 
 	// ActionType an action a player can take during their turn in a round
@@ -74,7 +153,8 @@ const (
 	MinPlayersToPlay = 2
 	// MaxTableSize once reached no more players can sit
 	MaxTableSize = 10
-	// MaxStandersSize once reached no more players can stand TODO what happens when standers is full and someone stands up?
+	// MaxStandersSize once reached no more players can watch the table, a
+	// player standing up from a seat then leaves the table instead
 	MaxStandersSize = 10
 	// AllIn takes the player all in
 	AllIn = ActionType(iota)
@@ -84,6 +164,12 @@ const (
 	Call = ActionType(iota)
 	// Fold your hand
 	Fold = ActionType(iota)
+	// Bet a total for the round, a call, raise or all in depending on the
+	// amount
+	Bet = ActionType(iota)
+	// disconnectedAllIn a disconnected player stays in the hand for the chips
+	// they have already bet
+	disconnectedAllIn = ActionType(iota)
 )
 
 // NewTable create a new table
@@ -99,10 +185,113 @@ func NewTable() *Table {
 
 // NewTableWithConfig create a new table with custom config
 func NewTableWithConfig(tableConfig TableConfig) *Table {
-	table := Table{TableConfig: tableConfig, tableMutex: sync.RWMutex{}}
+	table := Table{
+		TableConfig: tableConfig, tableMutex: sync.RWMutex{},
+		departures: make(map[string]departure),
+		commands:   make(chan *command),
+	}
 	return &table
 }
 
+// NewTableConfig create a config for a table whose big blind, and minimum
+// raise, is minBet
+func NewTableConfig(minBet int, timeToBet time.Duration,
+	timeBetweenHands time.Duration) TableConfig {
+	return TableConfig{
+		minBet: minBet, timeToBet: timeToBet,
+		secondsBetweenHands: timeBetweenHands,
+	}
+}
+
+// WithShortStackBlinds a copy of the config where players that cannot cover
+// the blinds post what they have and play all in rather than being stood up,
+// as in a tournament
+func (config TableConfig) WithShortStackBlinds() TableConfig {
+	config.shortStackBlinds = true
+	return config
+}
+
+// WithDoubleBoard a copy of the config that deals two boards every hand,
+// each pot is split in half between the best hands on each board
+func (config TableConfig) WithDoubleBoard() TableConfig {
+	config.boards = 2
+	return config
+}
+
+// WithBlindModification a copy of the config that lets players, not only
+// admins, change the blinds
+func (config TableConfig) WithBlindModification() TableConfig {
+	config.allowBlindModification = true
+	return config
+}
+
+// WithRake a copy of the config that collects rake from each hand
+func (config TableConfig) WithRake(rake RakeConfig) TableConfig {
+	config.rake = rake
+	return config
+}
+
+// WithAudit a copy of the config that audits chip conservation during hands
+func (config TableConfig) WithAudit(mode AuditMode) TableConfig {
+	config.audit = mode
+	return config
+}
+
+// WithBuyIn a copy of the config that limits the chips a player can bring to
+// the table, zero for no limit
+func (config TableConfig) WithBuyIn(minBuyIn int, maxBuyIn int) TableConfig {
+	config.minBuyIn = minBuyIn
+	config.maxBuyIn = maxBuyIn
+	return config
+}
+
+// WithRatholeWindow a copy of the config that requires a player returning to
+// the table within window of leaving to buy in for at least the stack they
+// left with
+func (config TableConfig) WithRatholeWindow(window time.Duration) TableConfig {
+	config.ratholeWindow = window
+	return config
+}
+
+// RakeCollected the total rake collected over all hands played at the table
+func (table *Table) RakeCollected() int {
+	table.tableMutex.RLock()
+	defer table.tableMutex.RUnlock()
+	return table.rakeCollected
+}
+
+// AddHandListener call listener after each hand played at the table
+func (table *Table) AddHandListener(listener HandListener) {
+	table.tableMutex.Lock()
+	defer table.tableMutex.Unlock()
+	table.handListeners = append(table.handListeners, listener)
+}
+
+// AddStateListener call listener whenever the state of the table changes,
+// e.g. after each player's turn and each card dealt
+func (table *Table) AddStateListener(listener StateListener) {
+	table.tableMutex.Lock()
+	defer table.tableMutex.Unlock()
+	table.stateListeners = append(table.stateListeners, listener)
+}
+
+func (table *Table) notifyState(turn *Turn) {
+	table.tableMutex.RLock()
+	listeners := table.stateListeners
+	table.tableMutex.RUnlock()
+	table.callListeners(func() {
+		for _, listener := range listeners {
+			listener(table, turn)
+		}
+	})
+}
+
+// NewRoundAction create an action for a player to take on their turn, bet is
+// the player's total bet for the round when raising or going all in
+func NewRoundAction(actionType ActionType, bet int) RoundAction {
+	return RoundAction{actionType: actionType, bet: bet}
+}
+
 // NewPlayer create a new player
 func NewPlayer(name string) *Player {
 	return NewPlayerWithFunds(name, 0)
@@ -118,26 +307,37 @@ func NewPlayerWithFunds(name string, funds int) *Player {
 }
 
 func (table *Table) validLBlind(player *Player) bool {
-	return player.Funds >= table.TableConfig.minBet/2
+	return player.Funds >= table.TableConfig.smallBlindAmount()
 }
 
 func (table *Table) validBBlind(player *Player) bool {
 	return player.Funds >= table.TableConfig.minBet
 }
 
-// Returns ring starting at the dealer
+// Returns ring starting at the dealer. Players waiting for the big blind
+// after changing seats sit out unless they are in it, or the hand could not be
+// played without them.
 func (table *Table) playersForHand() (*ring.Ring, Pot) {
 	mainPot := SubPot{make(map[*Player]struct{}), 0}
 	index := (table.DealerIndex + 1) % len(table.Players)
-	var playersPlaying []*Player
+	var playersPlaying, waiting []*Player
 	for i := 0; i < len(table.Players); i++ {
 		player := table.Players[index]
-		if player != nil {
-			if player.Funds <= 0 ||
-				len(playersPlaying) == 0 && !table.validLBlind(player) ||
-				len(playersPlaying) == 1 && !table.validBBlind(player) {
+		if player != nil && player.waitForBigBlind && len(playersPlaying) != 1 {
+			player.Playing = false
+			waiting = append(waiting, player)
+		} else if player != nil {
+			shortStack := !table.TableConfig.shortStackBlinds &&
+				(len(playersPlaying) == 0 && !table.validLBlind(player) ||
+					len(playersPlaying) == 1 && !table.validBBlind(player))
+			if player.Funds <= 0 || shortStack {
 				player.Standing = true
 				table.Players[index] = nil
+				table.cancelSeatChange(player)
+				table.standAside(player)
+				if err := table.cashOut(player); err != nil {
+					log.Println(err)
+				}
 			} else {
 				playersPlaying = append(playersPlaying, player)
 				mainPot.Players[player] = struct{}{}
@@ -145,6 +345,18 @@ func (table *Table) playersForHand() (*ring.Ring, Pot) {
 		}
 		index = (index + 1) % len(table.Players)
 	}
+	if len(playersPlaying) < MinPlayersToPlay && len(waiting) > 0 {
+		for _, p := range waiting {
+			p.waitForBigBlind = false
+		}
+		return table.playersForHand()
+	}
+	for _, p := range playersPlaying {
+		p.waitForBigBlind = false
+	}
+	if len(playersPlaying) == 0 {
+		return nil, Pot{MainPot: mainPot, SidePots: []SubPot{}}
+	}
 	out := ring.New(len(playersPlaying))
 	for _, p := range playersPlaying {
 		out.Value = p
@@ -167,26 +379,191 @@ func (table *Table) incrementDealerIndex() error {
 	return errors.New("incrementdealerindex: could not find next dealer")
 }
 
-// SitDown sit down the player at the table and seat TODO this should probably be an async action
+// SitDown sit down the player at the table and seat, AnySeat for the first
+// free seat or RandomSeat for a free seat chosen at random
 func (table *Table) SitDown(player *Player, seat int) error {
-	table.tableMutex.Lock()
-	defer table.tableMutex.Unlock()
-	if player.Funds < table.TableConfig.minBet {
+	return table.do(func() error {
+		return table.sitDown(player, seat)
+	})
+}
+
+// sitDown sit the player at seat, which may be AnySeat or RandomSeat
+func (table *Table) sitDown(player *Player, seat int) error {
+	seat, err := table.chooseSeat(seat)
+	if err != nil {
+		return err
+	} else if player.Funds < table.TableConfig.minBet {
 		return errors.New("Player has insufficient funds to sit")
-	} else if seat >= MaxTableSize {
+	} else if err := table.validBuyIn(player); err != nil {
+		return err
+	} else if seat < 0 || seat >= MaxTableSize {
 		return errors.New("Seat, " + fmt.Sprint(seat) +
-			" is greater than max table size, " + fmt.Sprint(MaxTableSize))
+			" is outside the table of size " + fmt.Sprint(MaxTableSize))
+	} else if offer := table.seatOfferedTo(seat); offer != nil &&
+		offer != player {
+		return fmt.Errorf("Seat %d is offered to %s from the waitlist", seat,
+			offer.Name)
+	} else if changer := table.seatRequestedBy(seat); changer != nil &&
+		table.Players[seat] == nil {
+		return fmt.Errorf("Seat %d is requested by %s for a seat change", seat,
+			changer.Name)
 	} else if table.Players[seat] == nil {
+		table.leaveStanders(player)
 		table.Players[seat] = player
+		player.table = table
+		player.Standing = false
 		return nil
 	} else {
 		return errors.New("Seat is occupied, " + fmt.Sprint(seat))
 	}
 }
 
-// StandUp - TODO this should likely be moved to async actions
+// Seat the player at seat without checking their buy in, e.g. when a
+// tournament moves a player and their stack from another table
+func (table *Table) Seat(player *Player, seat int) error {
+	return table.do(func() error {
+		return table.seat(player, seat)
+	})
+}
+
+func (table *Table) seat(player *Player, seat int) error {
+	if seat < 0 || seat >= MaxTableSize {
+		return fmt.Errorf("seat: seat %d is outside the table", seat)
+	} else if table.Players[seat] != nil {
+		return errors.New("seat: seat is occupied, " + fmt.Sprint(seat))
+	}
+	table.leaveStanders(player)
+	table.Players[seat] = player
+	player.table = table
+	player.Standing = false
+	return nil
+}
+
+// SeatInFirstEmpty seat the player, see Seat, in the first of seats that is
+// empty, returning the seat
+func (table *Table) SeatInFirstEmpty(player *Player, seats []int) (int, error) {
+	chosen := -1
+	err := table.do(func() error {
+		for _, seat := range seats {
+			if seat >= 0 && seat < MaxTableSize && table.Players[seat] == nil {
+				chosen = seat
+				return table.seat(player, seat)
+			}
+		}
+		return errors.New("seat: there is no empty seat")
+	})
+	return chosen, err
+}
+
+// SeatedFromDealer the seated players in order from the dealer's left, the
+// dealer last
+func (table *Table) SeatedFromDealer() []*Player {
+	var seated []*Player
+	table.do(func() error {
+		for i := 1; i <= len(table.Players); i++ {
+			player := table.Players[(table.DealerIndex+i)%len(table.Players)]
+			if player != nil {
+				seated = append(seated, player)
+			}
+		}
+		return nil
+	})
+	return seated
+}
+
+// Player the player named name sitting at or watching the table, nil if
+// there is none
+func (table *Table) Player(name string) *Player {
+	var player *Player
+	table.do(func() error {
+		for _, p := range append(table.Players[:], table.Standers[:]...) {
+			if p != nil && p.Name == name {
+				player = p
+			}
+		}
+		return nil
+	})
+	return player
+}
+
+// Stacks the chips of each seated player, false while a hand is being played
+// and some of their chips may be in its pots
+func (table *Table) Stacks() (map[*Player]int, bool) {
+	var stacks map[*Player]int
+	table.do(func() error {
+		if table.Hand != nil && !table.Hand.HandDone {
+			return nil
+		}
+		stacks = make(map[*Player]int)
+		for _, p := range table.Players {
+			if p != nil {
+				stacks[p] = p.Funds + p.BetAmount + p.pendingChips
+			}
+		}
+		return nil
+	})
+	return stacks, stacks != nil
+}
+
+// Unseat remove the player from their seat without cashing them out, only
+// between hands
+func (table *Table) Unseat(player *Player) error {
+	return table.do(func() error {
+		return table.unseat(player)
+	})
+}
+
+func (table *Table) unseat(player *Player) error {
+	for i, p := range table.Players {
+		if p == player {
+			table.Players[i] = nil
+			player.Playing = false
+			player.table = nil
+			table.cancelSeatChange(player)
+			table.offerSeats()
+			return nil
+		}
+	}
+	return errors.New("unseat: player is not sitting at this table")
+}
+
+// StandUp the player stands up from their table once the current hand is
+// finished, or before the next is dealt
 func (player *Player) StandUp() {
-	player.WantToStandUp = true
+	table := player.table
+	if table == nil {
+		player.WantToStandUp = true
+		return
+	}
+	table.do(func() error {
+		player.WantToStandUp = true
+		return nil
+	})
+}
+
+// StandUp the player stands up from the table and cashes out, immediately if
+// they are not playing the current hand and once it is finished otherwise
+func (table *Table) StandUp(player *Player) error {
+	return table.do(func() error {
+		if !table.isSeated(player) {
+			return errors.New("standup: player is not sitting at this table")
+		}
+		hand := table.Hand
+		if hand != nil && !hand.HandDone {
+			for _, p := range hand.DealtIn {
+				if p == player {
+					player.WantToStandUp = true
+					return nil
+				}
+			}
+		}
+		if err := table.standUp(player); err != nil {
+			return err
+		} else if hand == nil || hand.HandDone {
+			table.applySeatChanges()
+		}
+		return nil
+	})
 }
 
 func (table *Table) standUp(player *Player) error {
@@ -196,7 +573,10 @@ func (table *Table) standUp(player *Player) error {
 			table.Players[i].Standing = true
 			table.Players[i].WantToStandUp = false
 			table.Players[i] = nil
-			return nil
+			table.recordDeparture(player)
+			table.cancelSeatChange(player)
+			table.standAside(player)
+			return table.cashOut(player)
 		}
 	}
 	return errors.New("Player is not sitting at this table")
@@ -222,8 +602,15 @@ func (player Player) String() string {
 
 // String table's string
 func (table *Table) String() string {
-	table.tableMutex.RLock()
-	defer table.tableMutex.RUnlock()
+	var out string
+	table.do(func() error {
+		out = table.string()
+		return nil
+	})
+	return out
+}
+
+func (table *Table) string() string {
 	out := "Table:\n"
 	if len(table.Hand.Board) > 0 {
 		out += "Board="
@@ -1,11 +1,61 @@
 package model
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
+
+	"github.com/chehsunliu/poker"
 )
 
+// waitForTurn waits for the table to ask the player at seat for their action
+func waitForTurn(table *Table, seat int) {
+	for retries := 0; retries < 100; retries++ {
+		if table.State(nil).BetTurnSeat == seat {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// holdBetweenHands holds the table once each hand is finished, before the
+// next is dealt, sending a function that resumes play on the channel returned
+func holdBetweenHands(table *Table) <-chan func() {
+	held := make(chan func())
+	table.AddHandListener(func(table *Table, result *HandResult) {
+		resume := make(chan struct{})
+		held <- func() { close(resume) }
+		<-resume
+	})
+	return held
+}
+
+// playingHand mark the table as playing a hand, with a goroutine making the
+// changes queued for it, until stop is called
+func playingHand(table *Table) (stop func()) {
+	table.tableMutex.Lock()
+	table.playing = true
+	table.startCommands()
+	table.tableMutex.Unlock()
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case cmd := <-table.commands:
+				cmd.run()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		table.stopPlaying()
+	}
+}
+
 func TestNextBetter(t *testing.T) {
 	table := NewTable()
 	table.SitDown(&Player{Name: "Anna", Funds: 200}, 0) // dealer
@@ -86,11 +136,11 @@ func TestPlayAllIn(t *testing.T) {
 	table.Players[2].ActionChan <- RoundAction{Raise, 400}
 	table.Players[0].ActionChan <- RoundAction{Call, 400}
 	retries := 0
-	for table.playing && retries < 5 {
+	for table.Playing() && retries < 5 {
 		time.Sleep(time.Millisecond)
 		retries++
 	}
-	if table.playing {
+	if table.Playing() {
 		t.Error("table should be done playing")
 	}
 	fmt.Println(table)
@@ -118,11 +168,11 @@ func TestPlayFoldWin(t *testing.T) {
 	table.Players[2].ActionChan <- RoundAction{Fold, 0}
 	fmt.Println(table)
 	retries := 0
-	for table.playing && retries < 5 {
+	for table.Playing() && retries < 5 {
 		time.Sleep(time.Millisecond)
 		retries++
 	}
-	if table.playing {
+	if table.Playing() {
 		t.Error("table should be done playing")
 	}
 	totalFunds := paul.Funds + leto.Funds
@@ -145,19 +195,20 @@ func TestPlayFoldWithRematch(t *testing.T) {
 		fmt.Println(err)
 	}()
 	table.Players[2].ActionChan <- RoundAction{Fold, 0}
+	waitForTurn(table, 0)
 	table.Players[0].StandUp()
 	table.Players[2].StandUp()
 	table.Players[0].ActionChan <- RoundAction{Fold, 0}
 	fmt.Println(table)
-	totalFunds := paul.Funds + leto.Funds
 	retries := 0
-	for table.playing && retries < 5 {
+	for table.Playing() && retries < 5 {
 		time.Sleep(time.Millisecond)
 		retries++
 	}
-	if table.playing {
+	if table.Playing() {
 		t.Error("table should be done playing")
 	}
+	totalFunds := paul.Funds + leto.Funds
 	if totalFunds != 800 {
 		t.Error("expected 800 got", totalFunds)
 	}
@@ -172,6 +223,7 @@ func TestPlaySimple(t *testing.T) {
 	table.SitDown(leto, 0)
 	paul := NewPlayerWithFunds("Paul", 400)
 	table.SitDown(paul, 2)
+	held := holdBetweenHands(table)
 	go func() {
 		err := table.Play()
 		fmt.Println(err)
@@ -184,15 +236,17 @@ func TestPlaySimple(t *testing.T) {
 	table.Players[0].ActionChan <- RoundAction{Call, 0}
 	table.Players[2].ActionChan <- RoundAction{Call, 0}
 	table.Players[0].ActionChan <- RoundAction{Call, 0}
+	resume := <-held
 	table.Players[0].StandUp()
 	table.Players[2].StandUp()
+	resume()
 	fmt.Println(table)
 	retries := 0
-	for table.playing && retries < 5 {
+	for table.Playing() && retries < 5 {
 		time.Sleep(time.Millisecond)
 		retries++
 	}
-	if table.playing {
+	if table.Playing() {
 		t.Error("table should be done playing")
 	}
 	totalFunds := paul.Funds + leto.Funds
@@ -226,11 +280,11 @@ func TestPlayNoPlayerAtSeatZero(t *testing.T) {
 	table.Players[1].ActionChan <- RoundAction{Call, 0}
 	fmt.Println(table)
 	retries := 0
-	for table.playing && retries < 5 {
+	for table.Playing() && retries < 5 {
 		time.Sleep(time.Millisecond)
 		retries++
 	}
-	if table.playing {
+	if table.Playing() {
 		t.Error("table should be done playing")
 	}
 	totalFunds := paul.Funds + leto.Funds
@@ -278,11 +332,11 @@ func TestPlayFirstToBetChanges(t *testing.T) {
 	leto.ActionChan <- RoundAction{Call, 0}
 	fmt.Println(table)
 	retries := 0
-	for table.playing && retries < 5 {
+	for table.Playing() && retries < 5 {
 		time.Sleep(time.Millisecond)
 		retries++
 	}
-	if table.playing {
+	if table.Playing() {
 		t.Error("table should be done playing")
 	}
 	totalFunds := paul.Funds + leto.Funds + frank.Funds + beth.Funds
@@ -308,7 +362,7 @@ func TestTimeoutIsFold(t *testing.T) {
 	paul.StandUp()
 	time.Sleep(time.Millisecond * 3)
 	fmt.Println(table)
-	if table.playing {
+	if table.Playing() {
 		t.Error("table should be done playing")
 	}
 	totalFunds := paul.Funds + leto.Funds
@@ -316,3 +370,1148 @@ func TestTimeoutIsFold(t *testing.T) {
 		t.Error("expected 3200 got", totalFunds)
 	}
 }
+
+func cards(strs ...string) []poker.Card {
+	out := []poker.Card{}
+	for _, s := range strs {
+		out = append(out, poker.NewCard(s))
+	}
+	return out
+}
+
+func TestDescribeHand(t *testing.T) {
+	tests := []struct {
+		board, hole []string
+		expected    string
+	}{
+		{[]string{"Kh", "Kd", "7s", "2c", "9d"}, []string{"Ks", "7c"},
+			"Full House, Kings full of Sevens"},
+		{[]string{"Ah", "2d", "3s", "4c", "9d"}, []string{"5s", "Jc"},
+			"Straight, Five high"},
+		{[]string{"Ah", "Kh", "Qh", "Jh", "2d"}, []string{"Th", "Jc"},
+			"Royal Flush"},
+		{[]string{"Ah", "Ad", "8s", "8c", "2d"}, []string{"Th", "3c"},
+			"Two Pair, Aces and Eights"},
+		{[]string{"Ah", "Kd", "8s", "6c", "2d"}, []string{"Jh", "Jc"},
+			"Pair of Jacks"},
+		{[]string{"Ah", "Kd", "8s", "6c", "2d"}, []string{"Jh", "3c"},
+			"High Card, Ace"},
+	}
+	for _, test := range tests {
+		best, rank := bestHand(cards(test.board...), cards(test.hole...))
+		if len(best) != 5 {
+			t.Error("expected five cards got", best)
+		}
+		if description := describeHand(best, rank); description != test.expected {
+			t.Error("expected", test.expected, "got", description)
+		}
+	}
+}
+
+func TestFinishHandResult(t *testing.T) {
+	table := NewTable()
+	anna := NewPlayerWithFunds("Anna", 1000)
+	joe := NewPlayerWithFunds("Joe", 1000)
+	bob := NewPlayerWithFunds("Bob", 1000)
+	table.SitDown(anna, 0)
+	table.SitDown(joe, 1)
+	table.SitDown(bob, 2)
+	table.Hand = table.NewHand()
+	hand := table.Hand
+	hand.Board = cards("Kh", "Kd", "7s", "2c", "9d")
+	anna.Hole = cards("Ks", "7c")
+	joe.Hole = cards("9s", "9c")
+	bob.Hole = cards("Ac", "3d")
+	hand.Pot.SidePots = []SubPot{{
+		Players: map[*Player]struct{}{anna: {}, joe: {}, bob: {}}, Pot: 600,
+	}}
+	hand.Pot.MainPot = SubPot{
+		Players: map[*Player]struct{}{joe: {}}, Pot: 400,
+	}
+	hand.RoundDone, hand.HandDone = true, true
+	result, err := hand.FinishHand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Pots) != 2 {
+		t.Fatal("expected 2 pot results got", len(result.Pots))
+	}
+	side, main := result.Pots[0], result.Pots[1]
+	if side.Uncontested || len(side.Players) != 3 || len(side.Winners) != 1 {
+		t.Error("unexpected side pot result", side)
+	} else if side.Winners[0].Player != anna || side.Winners[0].Amount != 600 ||
+		side.Winners[0].Description != "Full House, Kings full of Sevens" {
+		t.Error("unexpected side pot winner", side.Winners[0])
+	}
+	if !main.Uncontested || main.Winners[0].Player != joe ||
+		main.Winners[0].Description != "" {
+		t.Error("unexpected main pot result", main)
+	}
+	if anna.Funds != 1600 || joe.Funds != 1400 || bob.Funds != 1000 {
+		t.Error("unexpected funds", anna.Funds, joe.Funds, bob.Funds)
+	}
+	if result.Winnings(joe) != 400 {
+		t.Error("expected joe to win 400 got", result.Winnings(joe))
+	}
+}
+
+func TestFinishHandEliminations(t *testing.T) {
+	table := NewTable()
+	anna := NewPlayerWithFunds("Anna", 1000)
+	joe := NewPlayerWithFunds("Joe", 1000)
+	bob := NewPlayerWithFunds("Bob", 1000)
+	table.SitDown(anna, 0)
+	table.SitDown(joe, 1)
+	table.SitDown(bob, 2)
+	table.Hand = table.NewHand()
+	hand := table.Hand
+	hand.DealtIn = []*Player{anna, joe, bob}
+	anna.Funds, joe.Funds, bob.Funds = 0, 500, 500
+	hand.Board = cards("Kh", "Kd", "7s", "2c", "9d")
+	anna.Hole = cards("3c", "4d")
+	joe.Hole = cards("Ac", "8h")
+	bob.Hole = cards("As", "8d")
+	hand.Pot.SidePots = []SubPot{{
+		Players: map[*Player]struct{}{anna: {}, joe: {}, bob: {}}, Pot: 900,
+	}}
+	hand.Pot.MainPot = SubPot{
+		Players: map[*Player]struct{}{joe: {}, bob: {}}, Pot: 400,
+	}
+	hand.RoundDone, hand.HandDone = true, true
+	result, err := hand.FinishHand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Eliminations) != 1 || result.Eliminations[0].Player != anna {
+		t.Fatal("expected Anna to be eliminated got", result.Eliminations)
+	}
+	if by := result.Eliminations[0].By; len(by) != 2 {
+		t.Error("expected Anna to be eliminated by the split pot's winners got",
+			by)
+	}
+}
+
+func TestRake(t *testing.T) {
+	rake := RakeConfig{
+		Percent: 5, Cap: 30, PlayerCaps: map[int]int{2: 10}, MinPot: 100,
+		NoFlopNoDrop: true,
+	}
+	tests := []struct {
+		board    []string
+		pot      int
+		dealtIn  int
+		expected int
+	}{
+		{[]string{"Kh", "Kd", "7s", "2c", "9d"}, 400, 3, 20},
+		{[]string{"Kh", "Kd", "7s", "2c", "9d"}, 1000, 3, 30},
+		{[]string{"Kh", "Kd", "7s", "2c", "9d"}, 1000, 2, 10},
+		{[]string{"Kh", "Kd", "7s", "2c", "9d"}, 90, 3, 0},
+		{[]string{}, 1000, 3, 0},
+	}
+	for _, test := range tests {
+		table := NewTableWithConfig(NewTable().TableConfig.WithRake(rake))
+		anna := NewPlayerWithFunds("Anna", 1000)
+		joe := NewPlayerWithFunds("Joe", 1000)
+		table.SitDown(anna, 0)
+		table.SitDown(joe, 1)
+		table.Hand = table.NewHand()
+		hand := table.Hand
+		hand.DealtIn = []*Player{anna, joe}
+		for len(hand.DealtIn) < test.dealtIn {
+			hand.DealtIn = append(hand.DealtIn, NewPlayer("Folded"))
+		}
+		hand.Board = cards(test.board...)
+		anna.Hole = cards("Ks", "7c")
+		joe.Hole = cards("9s", "9c")
+		hand.Pot.MainPot.Pot = test.pot
+		hand.RoundDone, hand.HandDone = true, true
+		result, err := hand.FinishHand()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Rake != test.expected {
+			t.Error("expected rake", test.expected, "got", result.Rake)
+		}
+		if anna.Funds+joe.Funds != 2000+test.pot-test.expected {
+			t.Error("expected winnings net of rake got", anna.Funds+joe.Funds)
+		}
+	}
+}
+
+func TestAuditStrict(t *testing.T) {
+	table := NewTableWithConfig(NewTable().TableConfig.WithAudit(AuditStrict))
+	leto := NewPlayerWithFunds("Leto", 400)
+	table.SitDown(leto, 0)
+	paul := NewPlayerWithFunds("Paul", 400)
+	table.SitDown(paul, 2)
+	table.Hand = table.NewHand()
+	hand := table.Hand
+	if err := hand.StartHand(); err != nil {
+		t.Fatal(err)
+	}
+	if err := hand.PlayerAction(paul, RoundAction{Call, 0}); err != nil {
+		t.Fatal(err)
+	}
+	if err := hand.audit("test"); err != nil {
+		t.Error("expected chips to be conserved", err)
+	}
+	leto.Funds += 50
+	var violation *AuditViolation
+	if err := hand.audit("test"); !errors.As(err, &violation) {
+		t.Fatal("expected an audit violation got", err)
+	}
+	if violation.Expected != 800 || violation.Actual != 850 {
+		t.Error("unexpected violation", violation)
+	}
+}
+
+func TestPlayAllInAudited(t *testing.T) {
+	table := NewTableWithConfig(TableConfig{
+		minBet: DefaultMinBet, timeToBet: time.Second * 30,
+		secondsBetweenHands: time.Second * 0, audit: AuditStrict,
+	})
+	leto := NewPlayerWithFunds("Leto", 400)
+	table.SitDown(leto, 0)
+	paul := NewPlayerWithFunds("Paul", 600)
+	table.SitDown(paul, 2)
+	errChan := make(chan error)
+	go func() {
+		errChan <- table.Play()
+	}()
+	leto.StandUp()
+	paul.StandUp()
+	paul.ActionChan <- RoundAction{Raise, 600}
+	leto.ActionChan <- RoundAction{AllIn, 400}
+	var violation *AuditViolation
+	if err := <-errChan; errors.As(err, &violation) {
+		t.Error("unexpected audit violation", err)
+	}
+	if totalFunds := paul.Funds + leto.Funds; totalFunds != 1000 {
+		t.Error("expected 1000 got", totalFunds)
+	}
+}
+
+func TestCreatePotsReturnsUncalledBet(t *testing.T) {
+	table := NewTable()
+	anna := NewPlayerWithFunds("Anna", 100)
+	joe := NewPlayerWithFunds("Joe", 300)
+	bob := NewPlayerWithFunds("Bob", 1200)
+	table.Players[0], table.Players[1], table.Players[2] = anna, joe, bob
+	table.Hand = table.NewHand()
+	hand := table.Hand
+	anna.Funds, joe.Funds, bob.Funds = 0, 0, 700
+	anna.BetAmount, anna.AllIn = 100, true
+	joe.BetAmount, joe.AllIn = 300, true
+	bob.BetAmount = 500
+	hand.CurrentBet = 500
+	hand.createPots()
+	if bob.Funds != 900 {
+		t.Error("expected uncalled 200 returned to Bob got", bob.Funds)
+	}
+	if hand.History[len(hand.History)-1] != "Uncalled bet (200) returned to Bob" {
+		t.Error("unexpected history", hand.History)
+	}
+	if len(hand.Pot.SidePots) != 1 || hand.Pot.SidePots[0].Pot != 300 ||
+		len(hand.Pot.SidePots[0].Players) != 3 {
+		t.Error("unexpected side pots", hand.Pot.SidePots)
+	}
+	if hand.Pot.MainPot.Pot != 400 || len(hand.Pot.MainPot.Players) != 2 {
+		t.Error("unexpected main pot", hand.Pot.MainPot)
+	}
+}
+
+func TestCreatePotsWithFoldedBet(t *testing.T) {
+	table := NewTable()
+	anna := NewPlayerWithFunds("Anna", 200)
+	bob := NewPlayerWithFunds("Bob", 1000)
+	table.Players[0], table.Players[1] = anna, bob
+	table.Hand = table.NewHand()
+	hand := table.Hand
+	anna.Funds, bob.Funds = 0, 700
+	anna.BetAmount, anna.AllIn = 100, true
+	bob.BetAmount = 300
+	hand.Round.foldedBets = []int{200}
+	hand.CurrentBet = 300
+	hand.createPots()
+	if bob.Funds != 800 {
+		t.Error("expected uncalled 100 returned to Bob got", bob.Funds)
+	}
+	if len(hand.Pot.SidePots) != 1 || hand.Pot.SidePots[0].Pot != 300 {
+		t.Error("unexpected side pots", hand.Pot.SidePots)
+	}
+	if hand.Pot.MainPot.Pot != 200 || len(hand.Pot.MainPot.Players) != 1 {
+		t.Error("unexpected main pot", hand.Pot.MainPot)
+	}
+}
+
+func TestBuyInAndCashOut(t *testing.T) {
+	table := NewTableWithConfig(NewTable().TableConfig.WithBuyIn(400, 1000))
+	bank := NewMemoryBank()
+	bank.Deposit("Anna", 1500)
+	table.Bank = bank
+	anna := NewPlayer("Anna")
+	if err := table.BuyIn(anna, 0, 300); err == nil {
+		t.Error("expected buy in below the minimum to fail")
+	}
+	if err := table.BuyIn(anna, 0, 1200); err == nil {
+		t.Error("expected buy in above the maximum to fail")
+	}
+	if bank.Balance("Anna") != 1500 || anna.Funds != 0 {
+		t.Error("failed buy ins should not move chips", bank.Balance("Anna"))
+	}
+	if err := table.BuyIn(anna, 0, 800); err != nil {
+		t.Fatal(err)
+	}
+	if bank.Balance("Anna") != 700 || anna.Funds != 800 {
+		t.Error("unexpected balance after buy in", bank.Balance("Anna"), anna.Funds)
+	}
+	if err := table.TopUp(anna, 300); err == nil {
+		t.Error("expected top up above the maximum to fail")
+	}
+	stop := playingHand(table)
+	if err := table.TopUp(anna, 200); err != nil {
+		t.Fatal(err)
+	}
+	if anna.Funds != 800 {
+		t.Error("top up should wait until the hand is over", anna.Funds)
+	}
+	table.Hand = &Hand{}
+	if _, ok := table.Stacks(); ok {
+		t.Error("expected no stacks while a hand is being played")
+	}
+	table.Hand.HandDone = true
+	if stacks, ok := table.Stacks(); !ok || stacks[anna] != 1000 {
+		t.Error("expected stacks to include top ups between hands got", stacks)
+	}
+	stop()
+	table.applyTopUps()
+	if anna.Funds != 1000 || bank.Balance("Anna") != 500 {
+		t.Error("unexpected funds after top up", anna.Funds, bank.Balance("Anna"))
+	}
+	table.standUp(anna)
+	if anna.Funds != 0 || bank.Balance("Anna") != 1500 {
+		t.Error("unexpected funds after cash out", anna.Funds, bank.Balance("Anna"))
+	}
+}
+
+func TestConcurrentBuyIns(t *testing.T) {
+	table := NewTable()
+	bank := NewMemoryBank()
+	bank.Deposit("Anna", 2000)
+	table.Bank = bank
+	anna := NewPlayer("Anna")
+	stop := playingHand(table)
+	errs := make(chan error)
+	for seat := 0; seat < 2; seat++ {
+		go func(seat int) {
+			errs <- table.BuyIn(anna, seat, 800)
+		}(seat)
+	}
+	failed := 0
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			failed++
+		}
+	}
+	stop()
+	if failed != 1 || anna.Funds != 800 || bank.Balance("Anna") != 1200 {
+		t.Error("expected exactly one buy in to succeed got", failed, anna.Funds,
+			bank.Balance("Anna"))
+	}
+}
+
+func TestRatholing(t *testing.T) {
+	table := NewTableWithConfig(NewTable().TableConfig.WithBuyIn(400, 1000).
+		WithRatholeWindow(time.Hour))
+	bank := NewMemoryBank()
+	bank.Deposit("Anna", 1000)
+	table.Bank = bank
+	anna := NewPlayer("Anna")
+	if err := table.BuyIn(anna, 0, 1000); err != nil {
+		t.Fatal(err)
+	}
+	anna.Funds = 1600
+	table.standUp(anna)
+	if err := table.BuyIn(anna, 0, 1000); err == nil {
+		t.Error("expected buy in for less than the stack left with to fail")
+	}
+	if err := table.BuyIn(anna, 0, 1600); err != nil {
+		t.Error("expected buy in for the stack left with to succeed", err)
+	}
+	table.standUp(anna)
+	left := table.departures["Anna"]
+	left.at = time.Now().Add(-2 * time.Hour)
+	table.departures["Anna"] = left
+	if err := table.BuyIn(anna, 0, 500); err != nil {
+		t.Error("expected buy in after the rathole window to succeed", err)
+	}
+}
+
+func TestBlindSchedule(t *testing.T) {
+	config, err := NewTable().TableConfig.WithBlindSchedule([]BlindLevel{
+		{SmallBlind: 50, BigBlind: 100, Hands: 2},
+		{Break: true, Duration: time.Millisecond},
+		{SmallBlind: 100, BigBlind: 200, Ante: 25, Hands: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := NewTableWithConfig(config)
+	if info := table.BlindInfo(); info.Level != 0 || info.HandsRemaining != 2 ||
+		info.Next == nil || !info.Next.Break {
+		t.Error("unexpected blind info", info)
+	}
+	table.advanceBlinds(context.Background())
+	if table.TableConfig.minBet != 100 {
+		t.Error("expected blinds to stay at level 0 got", table.TableConfig.minBet)
+	}
+	table.advanceBlinds(context.Background())
+	if info := table.BlindInfo(); info.Level != 2 || info.Next != nil {
+		t.Error("expected to move past the break to level 2 got", info)
+	}
+	if table.TableConfig.minBet != 200 || table.TableConfig.ante != 25 ||
+		table.TableConfig.smallBlindAmount() != 100 {
+		t.Error("level 2 blinds were not applied", table.TableConfig)
+	}
+	config, err = NewTable().TableConfig.WithBlindSchedule([]BlindLevel{
+		{SmallBlind: 50, BigBlind: 100, Hands: 1},
+		{Break: true, Duration: time.Hour},
+		{SmallBlind: 100, BigBlind: 200, Hands: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	table = NewTableWithConfig(config)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if err := table.advanceBlinds(ctx); !errors.Is(err,
+		context.DeadlineExceeded) {
+		t.Error("expected a break to end once ctx is done got", err)
+	}
+	// Changes are made during a break rather than waiting for it to end
+	table = NewTableWithConfig(config)
+	table.tableMutex.Lock()
+	table.playing = true
+	table.startCommands()
+	table.tableMutex.Unlock()
+	defer table.stopPlaying()
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	onBreak := make(chan error)
+	go func() {
+		onBreak <- table.advanceBlinds(ctx)
+	}()
+	if err := table.SitDown(NewPlayerWithFunds("Anna", 1000), 0); err != nil {
+		t.Error(err)
+	}
+	cancel()
+	if err := <-onBreak; !errors.Is(err, context.Canceled) {
+		t.Error("expected the break to end once ctx is done got", err)
+	}
+	if _, err := NewTable().TableConfig.WithBlindSchedule([]BlindLevel{
+		{SmallBlind: 50, BigBlind: 100},
+	}); err == nil {
+		t.Error("expected a level without a duration to be invalid")
+	}
+}
+
+func TestAntes(t *testing.T) {
+	config, _ := NewTable().TableConfig.WithBlindSchedule([]BlindLevel{
+		{SmallBlind: 100, BigBlind: 200, Ante: 25, Hands: 1},
+	})
+	table := NewTableWithConfig(config.WithAudit(AuditStrict))
+	anna := NewPlayerWithFunds("Anna", 1000)
+	joe := NewPlayerWithFunds("Joe", 1000)
+	bob := NewPlayerWithFunds("Bob", 1000)
+	table.SitDown(anna, 0)
+	table.SitDown(joe, 1)
+	table.SitDown(bob, 2)
+	table.Hand = table.NewHand()
+	if err := table.Hand.StartHand(); err != nil {
+		t.Fatal(err)
+	}
+	if table.Hand.Pot.MainPot.Pot != 75 {
+		t.Error("expected antes in the pot got", table.Hand.Pot.MainPot.Pot)
+	}
+	if joe.Funds != 875 || bob.Funds != 775 || anna.Funds != 975 {
+		t.Error("unexpected funds after antes and blinds", anna.Funds, joe.Funds,
+			bob.Funds)
+	}
+	// Anna is all in for less than the ante, she can only win what she
+	// matched of the others' antes
+	table = NewTableWithConfig(config.WithShortStackBlinds().
+		WithAudit(AuditStrict))
+	anna = NewPlayerWithFunds("Anna", 10)
+	table.Seat(anna, 0)
+	table.SitDown(NewPlayerWithFunds("Joe", 1000), 1)
+	table.SitDown(NewPlayerWithFunds("Bob", 1000), 2)
+	table.Hand = table.NewHand()
+	if err := table.Hand.StartHand(); err != nil {
+		t.Fatal(err)
+	}
+	pot := table.Hand.Pot
+	if len(pot.SidePots) != 1 || pot.SidePots[0].Pot != 30 ||
+		len(pot.SidePots[0].Players) != 3 || pot.MainPot.Pot != 30 ||
+		len(pot.MainPot.Players) != 2 {
+		t.Error("expected Anna's ante to close a side pot got", pot)
+	}
+	if _, ok := pot.MainPot.Players[anna]; ok || !anna.AllIn {
+		t.Error("expected Anna to be all in and out of the main pot")
+	}
+}
+
+func TestBombPot(t *testing.T) {
+	config, err := NewTable().TableConfig.WithBombPots(BombPotConfig{
+		Ante: 100, Every: 3, DoubleBoard: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := NewTableWithConfig(config.WithAudit(AuditStrict))
+	anna := NewPlayerWithFunds("Anna", 1000)
+	joe := NewPlayerWithFunds("Joe", 1000)
+	bob := NewPlayerWithFunds("Bob", 1000)
+	table.SitDown(anna, 0)
+	table.SitDown(joe, 1)
+	table.SitDown(bob, 2)
+	for i, expected := range []bool{false, false, true, false} {
+		if table.nextHandIsBombPot() != expected {
+			t.Error("expected hand", i, "bomb pot to be", expected)
+		}
+	}
+	table.VoteBombPot(anna)
+	if table.bombPot.scheduled {
+		t.Error("expected one vote of three not to schedule a bomb pot")
+	}
+	table.VoteBombPot(joe)
+	table.Hand = table.NewHand()
+	table.Hand.BombPot = table.nextHandIsBombPot()
+	if !table.Hand.BombPot {
+		t.Fatal("expected a majority vote to schedule a bomb pot")
+	}
+	if err := table.Hand.StartHand(); err != nil {
+		t.Fatal(err)
+	}
+	hand := table.Hand
+	if len(hand.Boards) != 2 || len(hand.Board) != 3 ||
+		hand.Pot.MainPot.Pot != 300 {
+		t.Error("expected a flop on two boards to a pot of 300 got", hand.Boards,
+			hand.Pot.MainPot.Pot)
+	}
+	if joe.BetAmount != 0 || bob.BetAmount != 0 || hand.CurrentBet != 0 {
+		t.Error("expected no blinds in a bomb pot")
+	}
+	if pRing(hand.BetTurn) != joe {
+		t.Error("expected the player after the button to act first got",
+			pRing(hand.BetTurn).Name)
+	}
+}
+
+func TestDoubleBoardBombPotShortAnte(t *testing.T) {
+	config, err := NewTable().TableConfig.WithShortStackBlinds().
+		WithBombPots(BombPotConfig{Ante: 100, DoubleBoard: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := NewTableWithConfig(config.WithAudit(AuditStrict))
+	anna := NewPlayerWithFunds("Anna", 50)
+	joe := NewPlayerWithFunds("Joe", 1000)
+	bob := NewPlayerWithFunds("Bob", 1000)
+	table.Seat(anna, 0)
+	table.Seat(joe, 1)
+	table.Seat(bob, 2)
+	table.ScheduleBombPot()
+	table.Hand = table.NewHand()
+	hand := table.Hand
+	hand.BombPot = table.nextHandIsBombPot()
+	if err := hand.StartHand(); err != nil {
+		t.Fatal(err)
+	}
+	if len(hand.Boards) != 2 || len(hand.Pot.SidePots) != 1 ||
+		hand.Pot.SidePots[0].Pot != 150 || hand.Pot.MainPot.Pot != 100 {
+		t.Fatal("expected Anna's short ante to close a side pot got", hand.Pot)
+	}
+	hand.Boards = [][]poker.Card{
+		cards("Kh", "Kd", "7s", "2c", "9d"), cards("Qh", "Jh", "4h", "2s", "3d"),
+	}
+	hand.Board = hand.Boards[0]
+	anna.Hole = cards("Ks", "5c")
+	joe.Hole = cards("Ah", "6h")
+	bob.Hole = cards("8c", "8d")
+	hand.RoundDone, hand.HandDone = true, true
+	result, err := hand.FinishHand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Anna wins half of the side pot on the first board, not the main pot
+	if result.Net[anna] != 25 || result.Net[joe] != 25 ||
+		result.Net[bob] != -50 {
+		t.Error("expected Anna to win only antes she matched got", result.Net)
+	}
+}
+
+func TestDoubleBoard(t *testing.T) {
+	table := NewTableWithConfig(NewTable().TableConfig.WithDoubleBoard())
+	anna := NewPlayerWithFunds("Anna", 1000)
+	joe := NewPlayerWithFunds("Joe", 1000)
+	table.SitDown(anna, 0)
+	table.SitDown(joe, 1)
+	table.Hand = table.NewHand()
+	hand := table.Hand
+	if err := hand.StartHand(); err != nil {
+		t.Fatal(err)
+	}
+	hand.Round.RoundDone = true
+	hand.Deal()
+	if len(hand.Boards) != 2 || len(hand.Boards[0]) != 3 ||
+		len(hand.Boards[1]) != 3 || len(hand.Board) != 3 {
+		t.Fatal("expected a flop on each of two boards got", hand.Boards)
+	}
+	hand.Boards = [][]poker.Card{
+		cards("Kh", "Kd", "7s", "2c", "9d"), cards("Qh", "Jh", "4h", "2s", "3d"),
+	}
+	hand.Board = hand.Boards[0]
+	anna.Hole = cards("Ks", "5c")
+	joe.Hole = cards("Ah", "6h")
+	hand.Pot.MainPot.Pot = 1001
+	hand.RoundDone, hand.HandDone = true, true
+	result, err := hand.FinishHand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	winners := result.Pots[0].Winners
+	if len(winners) != 2 || winners[0].Player != anna || winners[0].Board != 0 ||
+		winners[0].Amount != 501 || winners[1].Player != joe ||
+		winners[1].Board != 1 || winners[1].Amount != 500 {
+		t.Error("expected the pot to be split between the boards got", winners)
+	}
+	if winners[1].Description != "Flush, Ace high" {
+		t.Error("expected joe's hand to be described on the second board got",
+			winners[1].Description)
+	}
+	if len(result.Boards) != 2 {
+		t.Error("expected the result to report both boards got", result.Boards)
+	}
+}
+
+func TestDoubleBoardElimination(t *testing.T) {
+	table := NewTableWithConfig(NewTable().TableConfig.WithDoubleBoard())
+	anna := NewPlayerWithFunds("Anna", 1000)
+	joe := NewPlayerWithFunds("Joe", 1000)
+	table.SitDown(anna, 0)
+	table.SitDown(joe, 1)
+	table.Hand = table.NewHand()
+	hand := table.Hand
+	hand.DealtIn = []*Player{anna, joe}
+	anna.Funds, joe.Funds = 1000, 0
+	hand.Boards = [][]poker.Card{
+		cards("Kh", "Kd", "7s", "2c", "9d"), cards("Qh", "Jh", "4h", "2s", "3d"),
+	}
+	hand.Board = hand.Boards[0]
+	anna.Hole = cards("Ks", "Ah")
+	joe.Hole = cards("5c", "8d")
+	hand.Pot.MainPot = SubPot{
+		Players: map[*Player]struct{}{anna: {}, joe: {}}, Pot: 2000,
+	}
+	hand.RoundDone, hand.HandDone = true, true
+	result, err := hand.FinishHand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Eliminations) != 1 || result.Eliminations[0].Player != joe {
+		t.Fatal("expected Joe to be eliminated got", result.Eliminations)
+	}
+	if by := result.Eliminations[0].By; len(by) != 1 || by[0] != anna {
+		t.Error("expected Joe to be eliminated by Anna once got", by)
+	}
+}
+
+func TestBetActionAndState(t *testing.T) {
+	table := NewTable()
+	anna := NewPlayerWithFunds("Anna", 1000)
+	joe := NewPlayerWithFunds("Joe", 1000)
+	bob := NewPlayerWithFunds("Bob", 1000)
+	table.SitDown(anna, 0)
+	table.SitDown(joe, 2)
+	table.SitDown(bob, 4)
+	var turns []*Turn
+	table.AddStateListener(func(table *Table, turn *Turn) {
+		turns = append(turns, turn)
+	})
+	table.Hand = table.NewHand()
+	hand := table.Hand
+	if err := hand.StartHand(); err != nil {
+		t.Fatal(err)
+	}
+	state := table.State(anna)
+	if state.BetTurnSeat != 0 || state.CurrentBet != 200 {
+		t.Error("expected Anna to act facing 200 got", state.BetTurnSeat,
+			state.CurrentBet)
+	}
+	if len(state.Seats[0].Hole) != 2 || state.Seats[2].Hole != nil {
+		t.Error("expected only the viewer's hole cards", state.Seats)
+	}
+	if state.Config.SmallBlind != 100 || state.Config.BigBlind != 200 {
+		t.Error("unexpected config", state.Config)
+	}
+	// Anna raises, Joe calls and Bob goes all in, all with Bet
+	for _, bet := range []struct {
+		player *Player
+		amount int
+	}{{anna, 600}, {joe, 600}, {bob, 1000}} {
+		action := NewRoundAction(Bet, bet.amount)
+		if err := hand.PlayerAction(bet.player, action); err != nil {
+			t.Fatal(err)
+		}
+		hand.notify(&Turn{Player: bet.player, Bet: bet.player.BetAmount})
+	}
+	if hand.Round.CurrentBet != 1000 || !bob.AllIn {
+		t.Error("expected Bob all in for 1000 got", hand.Round.CurrentBet)
+	}
+	if err := hand.PlayerAction(anna, NewRoundAction(Bet, 700)); err == nil {
+		t.Error("expected a bet short of the current bet to fail")
+	}
+	expected := []string{"Anna raises to 600", "Joe calls 500",
+		"Bob is all in for 1000"}
+	for i, event := range expected {
+		if hand.History[len(hand.History)-3+i] != event {
+			t.Error("expected", event, "got", hand.History)
+		}
+	}
+	if len(turns) != 3 || turns[2].Player != bob || turns[2].Bet != 1000 {
+		t.Error("expected a turn for each action got", turns)
+	}
+}
+
+func TestModifyBlinds(t *testing.T) {
+	table := NewTable()
+	if err := table.ModifyBlinds(300, 200, 0); err == nil {
+		t.Error("expected a small blind over the big blind to fail")
+	}
+	if err := table.ModifyBlinds(100, 300, 25); err != nil {
+		t.Fatal(err)
+	}
+	config := table.Config()
+	if config.SmallBlind != 100 || config.BigBlind != 300 || config.Ante != 25 {
+		t.Error("unexpected config", config)
+	}
+	scheduled, _ := NewTable().TableConfig.WithBlindSchedule([]BlindLevel{
+		{SmallBlind: 100, BigBlind: 200, Hands: 5}})
+	if err := NewTableWithConfig(scheduled).ModifyBlinds(100, 200,
+		0); err == nil {
+		t.Error("expected a table with a blind schedule to refuse")
+	}
+}
+
+func TestModifyConfigBetweenHands(t *testing.T) {
+	table := NewTable()
+	stop := playingHand(table)
+	bigBlind, timeToBet := 400, 10*time.Second
+	next, err := table.ModifyConfig(ConfigChange{BigBlind: &bigBlind})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := table.ModifyConfig(ConfigChange{
+		TimeToBet: &timeToBet}); err != nil {
+		t.Fatal(err)
+	}
+	if next.BigBlind != 400 || table.Config().BigBlind != DefaultMinBet {
+		t.Error("expected the big blind to change from the next hand got",
+			next, table.Config())
+	}
+	zero := time.Duration(0)
+	if _, err := table.ModifyConfig(ConfigChange{TimeToBet: &zero}); err == nil {
+		t.Error("expected no time to bet to fail")
+	}
+	stop()
+	table.applyPendingConfig()
+	config := table.Config()
+	if config.BigBlind != 400 || config.TimeToBet != timeToBet {
+		t.Error("expected both changes to be applied got", config)
+	}
+}
+
+func TestPauseAndStop(t *testing.T) {
+	table := NewTableWithConfig(NewTableConfig(DefaultMinBet,
+		50*time.Millisecond, 0))
+	leto := NewPlayerWithFunds("Leto", 1000)
+	paul := NewPlayerWithFunds("Paul", 1000)
+	table.SitDown(leto, 0)
+	table.SitDown(paul, 1)
+	if err := table.Pause(); err == nil {
+		t.Error("expected a table that is not playing not to pause")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	played := make(chan error)
+	go func() {
+		played <- table.PlayContext(ctx)
+	}()
+	waitForTurn(table, 1)
+	if err := table.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	// The clock is stopped, Paul would have timed out otherwise
+	time.Sleep(100 * time.Millisecond)
+	select {
+	case paul.ActionChan <- RoundAction{Fold, 0}:
+		t.Error("expected no action to be taken while paused")
+	case <-time.After(10 * time.Millisecond):
+	}
+	if paul.Funds+paul.BetAmount != 1000 || !table.Paused() {
+		t.Error("expected Paul to still be deciding got", paul)
+	}
+	if state := table.State(nil); state.BetTurnSeat != 1 ||
+		state.TimeToAct <= 0 || state.TimeToAct > 50*time.Millisecond {
+		t.Error("expected Paul's clock to be stopped got", state.TimeToAct)
+	}
+	if err := table.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	paul.ActionChan <- RoundAction{Fold, 0}
+	cancel()
+	select {
+	case err := <-played:
+		if !errors.Is(err, context.Canceled) {
+			t.Error("expected the table to be stopped got", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the table to stop")
+	}
+	if table.Playing() || leto.Funds+paul.Funds != 2000 ||
+		leto.BetAmount+paul.BetAmount != 0 {
+		t.Error("expected every hand to be finished got", table)
+	}
+}
+
+func TestPauseAfterStop(t *testing.T) {
+	table := NewTableWithConfig(NewTableConfig(DefaultMinBet,
+		30*time.Second, 0))
+	leto := NewPlayerWithFunds("Leto", 1000)
+	paul := NewPlayerWithFunds("Paul", 1000)
+	table.SitDown(leto, 0)
+	table.SitDown(paul, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	played := make(chan error)
+	go func() {
+		played <- table.PlayContext(ctx)
+	}()
+	waitForTurn(table, 1)
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	if err := table.Pause(); err == nil {
+		t.Error("expected a table finishing its last hand not to pause")
+	}
+	paul.ActionChan <- RoundAction{Fold, 0}
+	select {
+	case err := <-played:
+		if !errors.Is(err, context.Canceled) {
+			t.Error("expected the table to be stopped got", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the table to stop")
+	}
+}
+
+func TestChangesDuringPlay(t *testing.T) {
+	table := NewTableWithConfig(NewTableConfig(DefaultMinBet,
+		30*time.Second, 0))
+	leto := NewPlayerWithFunds("Leto", 1000)
+	paul := NewPlayerWithFunds("Paul", 1000)
+	anna := NewPlayerWithFunds("Anna", 1000)
+	table.SitDown(leto, 0)
+	table.SitDown(paul, 1)
+	played := make(chan error)
+	go func() {
+		played <- table.Play()
+	}()
+	waitForTurn(table, 1)
+	// Made by the playing goroutine while it waits for Paul to act
+	if err := table.SitDown(anna, 4); err != nil {
+		t.Fatal(err)
+	}
+	state := table.State(anna)
+	if state.Seats[4] == nil || state.Seats[4].Playing {
+		t.Error("expected Anna to sit out the hand got", state.Seats[4])
+	}
+	if err := table.StandUp(anna); err != nil {
+		t.Fatal(err)
+	}
+	if err := table.StandUp(anna); err == nil {
+		t.Error("expected Anna to have stood up immediately")
+	}
+	if err := table.StandUp(paul); err != nil {
+		t.Fatal(err)
+	}
+	if state := table.State(paul); state.Seats[1] == nil {
+		t.Error("expected Paul to stand up once the hand is finished")
+	}
+	paul.ActionChan <- RoundAction{Fold, 0}
+	select {
+	case <-played:
+	case <-time.After(time.Second):
+		t.Fatal("expected the table to stop once Paul stood up")
+	}
+	state = table.State(leto)
+	if state.Seats[1] != nil || state.Seats[4] != nil || anna.Funds != 1000 ||
+		leto.Funds+paul.Funds != 2000 {
+		t.Error("unexpected table after play", state.Seats, anna, paul)
+	}
+}
+
+func TestStandersAndWaitlist(t *testing.T) {
+	table := NewTableWithConfig(NewTableConfig(DefaultMinBet, 30*time.Second,
+		0).WithSeatOfferTimeout(10 * time.Millisecond))
+	expired := make(chan struct{}, 1)
+	table.AddWaitlistListener(func(table *Table) {
+		expired <- struct{}{}
+	})
+	var seated []*Player
+	for i := 0; i < MaxTableSize; i++ {
+		player := NewPlayerWithFunds(fmt.Sprint("Player", i), 1000)
+		seated = append(seated, player)
+		table.SitDown(player, i)
+	}
+	anna := NewPlayerWithFunds("Anna", 1000)
+	bob := NewPlayerWithFunds("Bob", 1000)
+	for _, player := range []*Player{anna, bob} {
+		if err := table.JoinWaitlist(player); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := table.JoinWaitlist(anna); err == nil || !table.Watching(anna) {
+		t.Error("expected Anna to watch and wait once")
+	}
+	for i := 2; i < MaxStandersSize; i++ {
+		if err := table.Watch(NewPlayer(fmt.Sprint("Stander", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := table.Watch(NewPlayer("Joe")); !errors.Is(err, ErrStandersFull) {
+		t.Error("expected the standers to be full got", err)
+	}
+	// With the standers full the player leaves the table, their seat is
+	// offered to Anna
+	if err := table.StandUp(seated[0]); err != nil {
+		t.Fatal(err)
+	}
+	state := table.State(nil)
+	if table.Watching(seated[0]) || len(state.SeatOffers) != 1 ||
+		state.SeatOffers[0].Name != "Anna" || state.SeatOffers[0].Seat != 0 {
+		t.Error("expected seat 0 to be offered to Anna got", state.SeatOffers)
+	}
+	if err := table.SitDown(seated[0], 0); err == nil {
+		t.Error("expected an offered seat not to be taken")
+	}
+	if seat, err := table.AcceptSeat(anna, 0); err != nil || seat != 0 {
+		t.Fatal("expected Anna to sit at seat 0 got", seat, err)
+	}
+	if table.Watching(anna) || table.State(nil).Seats[0].Name != "Anna" {
+		t.Error("expected Anna to be seated")
+	}
+	// Bob does not accept the seat in time and loses his place
+	if err := table.StandUp(seated[1]); err != nil {
+		t.Fatal(err)
+	}
+	if !table.Watching(seated[1]) {
+		t.Error("expected the player to watch after standing up")
+	}
+	select {
+	case <-expired:
+	case <-time.After(time.Second):
+		t.Fatal("expected Bob's seat offer to expire")
+	}
+	state = table.State(nil)
+	if len(state.SeatOffers) != 0 || len(state.Waitlist) != 0 ||
+		!table.Watching(bob) {
+		t.Error("expected Bob to watch without waiting got", state.Waitlist)
+	}
+	if err := table.SitDown(bob, 1); err != nil {
+		t.Error("expected the seat to be free got", err)
+	}
+}
+
+func TestSeatSelectionAndSeatChanges(t *testing.T) {
+	table := NewTable()
+	anna := NewPlayerWithFunds("Anna", 1000)
+	bob := NewPlayerWithFunds("Bob", 1000)
+	carl := NewPlayerWithFunds("Carl", 1000)
+	dan := NewPlayerWithFunds("Dan", 1000)
+	if err := table.SitDown(anna, AnySeat); err != nil ||
+		table.seatOf(anna) != 0 {
+		t.Fatal("expected Anna to take the first free seat got", err)
+	}
+	if err := table.SitDown(bob, RandomSeat); err != nil ||
+		table.seatOf(bob) <= 0 {
+		t.Fatal("expected Bob to take a random free seat got", err)
+	}
+	table.Unseat(bob)
+	table.SitDown(bob, 1)
+	table.SitDown(carl, 2)
+	table.SitDown(dan, 4)
+	if err := table.RequestSeatChange(dan, 2); err != nil {
+		t.Fatal(err)
+	}
+	if state := table.State(nil); len(state.SeatChanges) != 1 ||
+		state.SeatChanges[0].Name != "Dan" {
+		t.Error("expected Dan to wait for Carl's seat got", state.SeatChanges)
+	}
+	// Dan moves to seat 3, where he would be first to act after the blinds
+	if err := table.RequestSeatChange(dan, 3); err != nil {
+		t.Fatal(err)
+	}
+	if table.seatOf(dan) != 3 || len(table.State(nil).SeatChanges) != 0 {
+		t.Fatal("expected Dan to move to the free seat")
+	}
+	hand := table.NewHand()
+	for _, p := range []*Player{hand.SmallBlind(), hand.BigBlind(),
+		hand.Dealer()} {
+		if p == dan {
+			t.Error("expected Dan to sit out until the big blind")
+		}
+	}
+	if hand.Players.Len() != 3 || !dan.waitForBigBlind {
+		t.Error("expected Dan to wait for the big blind got", hand.Players.Len())
+	}
+	table.DealerIndex = 1
+	hand = table.NewHand()
+	if hand.BigBlind() != dan || dan.waitForBigBlind {
+		t.Error("expected Dan to be dealt in as the big blind got",
+			hand.BigBlind())
+	}
+	// During a hand the request waits, and the seat is held for Dan
+	table.Hand = hand
+	stop := playingHand(table)
+	table.RequestSeatChange(dan, 5)
+	table.StandUp(anna)
+	if err := table.SitDown(anna, 5); err == nil {
+		t.Error("expected a seat requested for a seat change not to be taken")
+	}
+	if table.seatOf(dan) != 3 {
+		t.Error("expected Dan to stay in his seat until the hand is over")
+	}
+	stop()
+	hand.HandDone = true
+	table.applySeatChanges()
+	if table.seatOf(dan) != 5 {
+		t.Error("expected Dan to move between hands got", table.seatOf(dan))
+	}
+}
+
+func TestSeatChangeOfBustedPlayer(t *testing.T) {
+	table := NewTable()
+	anna := NewPlayerWithFunds("Anna", 1000)
+	bob := NewPlayerWithFunds("Bob", 1000)
+	carl := NewPlayerWithFunds("Carl", 1000)
+	table.SitDown(anna, 0)
+	table.SitDown(bob, 1)
+	table.SitDown(carl, 2)
+	table.Hand = &Hand{}
+	if err := table.RequestSeatChange(carl, 5); err != nil {
+		t.Fatal(err)
+	}
+	// Carl busts, so is stood up as the next hand is dealt
+	carl.Funds = 0
+	table.Hand = table.NewHand()
+	if table.seatOf(carl) >= 0 || len(table.seatChanges) != 0 {
+		t.Error("expected Carl's seat change to be cancelled got",
+			table.seatChanges)
+	}
+	table.seatChanges = append(table.seatChanges,
+		seatChange{player: carl, seat: 5})
+	table.Hand.HandDone = true
+	table.applySeatChanges()
+	if len(table.seatChanges) != 0 || table.Players[5] != nil {
+		t.Error("expected the request of a player no longer seated to be dropped")
+	}
+	if err := table.SitDown(NewPlayerWithFunds("Dan", 1000), 5); err != nil {
+		t.Error("expected the seat to be free got", err)
+	}
+}
+
+func TestSeatInFirstEmptyAndSeatedFromDealer(t *testing.T) {
+	table := NewTable()
+	anna, bob, carl := NewPlayer("Anna"), NewPlayer("Bob"), NewPlayer("Carl")
+	table.Seat(anna, 2)
+	if seat, err := table.SeatInFirstEmpty(bob, []int{2, 5, 0}); err != nil ||
+		seat != 5 {
+		t.Error("expected Bob to be seated in the first empty seat got", seat, err)
+	}
+	if _, err := table.SeatInFirstEmpty(carl, []int{2, 5}); err == nil {
+		t.Error("expected seating with no empty seat to fail")
+	}
+	table.Seat(carl, 0)
+	table.DealerIndex = 2
+	seated := table.SeatedFromDealer()
+	if len(seated) != 3 || seated[0] != bob || seated[1] != carl ||
+		seated[2] != anna {
+		t.Error("expected Bob, Carl then the dealer Anna got", seated)
+	}
+}
+
+func TestDisconnectProtection(t *testing.T) {
+	table := NewTableWithConfig(NewTableConfig(DefaultMinBet,
+		10*time.Millisecond, 0).WithDisconnectProtection(DisconnectProtection{
+		Time: 20 * time.Millisecond, Protections: 1, AllIn: true,
+	}))
+	leto := NewPlayerWithFunds("Leto", 1000)
+	paul := NewPlayerWithFunds("Paul", 1000)
+	table.SitDown(leto, 0)
+	table.SitDown(paul, 1)
+	table.SetConnected(paul, false)
+	ctx, cancel := context.WithCancel(context.Background())
+	results := make(chan *HandResult, 1)
+	table.AddHandListener(func(table *Table, result *HandResult) {
+		cancel()
+		results <- result
+	})
+	played := make(chan error)
+	go func() {
+		played <- table.PlayContext(ctx)
+	}()
+	// Leto checks whenever it's his turn, Paul's clock runs out while he is
+	// disconnected
+	go func() {
+		for {
+			select {
+			case leto.ActionChan <- NewRoundAction(Call, 0):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	var result *HandResult
+	select {
+	case result = <-results:
+	case <-time.After(time.Second):
+		t.Fatal("expected the hand to finish")
+	}
+	<-played
+	won := 0
+	for _, pot := range result.Pots {
+		for _, winner := range pot.Winners {
+			won += winner.Amount
+		}
+	}
+	history := table.Hand.History
+	if won != 200 || leto.Funds+paul.Funds != 2000 ||
+		paul.protectionsUsed != 1 {
+		t.Error("expected Paul to play the hand for his small blind got", won,
+			history)
+	}
+	expected := []string{"Paul is disconnected, 20ms to reconnect",
+		"Paul is disconnected, all in for 100"}
+	for i, event := range expected {
+		if i+2 >= len(history) || history[i+2] != event {
+			t.Error("expected", event, "got", history)
+		}
+	}
+	// Without a protection left Paul folds as soon as his clock runs out
+	table.Hand = table.NewHand()
+	if err := table.Hand.StartHand(); err != nil {
+		t.Fatal(err)
+	}
+	action, _ := table.Hand.getPlayerAction(paul, time.Millisecond)
+	if action.actionType != Fold {
+		t.Error("expected Paul to fold got", action)
+	}
+}
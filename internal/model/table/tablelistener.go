@@ -7,78 +7,186 @@ import (
 	"time"
 )
 
-// Play rounds at the table
+// Play rounds at the table until a hand fails, see PlayContext
 func (table *Table) Play() error {
+	return table.PlayContext(context.Background())
+}
+
+// PlayContext play rounds at the table until ctx is done or a hand fails.
+// Once ctx is done the hand being played is finished, resuming it if it was
+// paused, and ctx's error is returned.
+func (table *Table) PlayContext(ctx context.Context) error {
+	table.tableMutex.Lock()
 	if table.playing {
+		table.tableMutex.Unlock()
 		return errors.New("play: table already playing")
 	}
 	table.playing = true
+	table.startCommands()
+	table.tableMutex.Unlock()
+	table.pause.stop(false)
+	defer table.stopPlaying()
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		select {
+		case <-ctx.Done():
+			table.pause.stop(true)
+		case <-stopped:
+		}
+	}()
+	table.startBlindClock()
 	for {
+		if err := table.waitWhilePaused(ctx); err != nil {
+			return err
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+		table.applyPendingConfig()
+		table.applyTopUps()
+		table.tableMutex.Lock()
+		table.applySeatChanges()
+		table.tableMutex.Unlock()
 		table.Hand = table.NewHand()
-		log.Println("Dealing next hand, dealer is", pRing(table.Hand.Players).Name)
+		table.Hand.BombPot = table.nextHandIsBombPot()
 		if err := table.Hand.StartHand(); err != nil {
-			table.playing = false
 			return err
 		}
-		table.Hand.ListenForPlayerActions()
+		log.Println("Dealt next hand, dealer is", table.Hand.Dealer().Name)
+		table.notifyState(nil)
+		if err := table.Hand.ListenForPlayerActions(); err != nil {
+			return err
+		}
 		for !table.Hand.HandDone {
 			table.Hand.Deal()
-			table.Hand.ListenForPlayerActions()
+			table.notifyState(nil)
+			if err := table.Hand.ListenForPlayerActions(); err != nil {
+				return err
+			}
 			if len(table.Hand.Board) == 5 {
 				table.Hand.HandDone = true
 			}
 		}
-		if err := table.Hand.FinishHand(); err != nil {
+		result, err := table.Hand.FinishHand()
+		if err != nil {
 			log.Println(err)
-			table.playing = false
 			return err
 		}
-		time.Sleep(time.Second * table.TableConfig.secondsBetweenHands)
+		table.tableMutex.Lock()
+		table.rakeCollected += result.Rake
+		listeners := table.handListeners
+		table.tableMutex.Unlock()
+		table.callListeners(func() {
+			for _, listener := range listeners {
+				listener(table, result)
+			}
+		})
+		table.notifyState(nil)
+		table.sleep(ctx, table.TableConfig.secondsBetweenHands)
 		for _, p := range table.Players {
 			if p != nil && p.WantToStandUp {
 				table.standUp(p)
 			}
 		}
+		if err := table.advanceBlinds(ctx); err != nil {
+			return err
+		}
+		if table.Hand.BombPot {
+			continue
+		}
 		if err := table.incrementDealerIndex(); err != nil {
 			log.Println(err)
-			table.playing = false
 			return err
 		}
 	}
 }
 
-// ListenForPlayerActions get each player's action for the round of bets
-func (hand *Hand) ListenForPlayerActions() {
+func (table *Table) stopPlaying() {
+	table.tableMutex.Lock()
+	defer table.tableMutex.Unlock()
+	table.playing = false
+	table.stopCommands()
+}
+
+// ListenForPlayerActions get each player's action for the round of bets, an
+// error is only returned if the hand fails a strict chip audit
+func (hand *Hand) ListenForPlayerActions() error {
 	for !hand.Round.RoundDone && !hand.BettingDone && !hand.HandDone {
 		success := false
 		player := pRing(hand.Round.BetTurn)
 		timeRemaining := hand.TableConfig.timeToBet
 		for !success {
-			ctx, cancel := context.WithTimeout(context.Background(), timeRemaining)
-			defer cancel()
-			t := time.Now()
-			err := hand.PlayerAction(player, getPlayerAction(ctx, player))
-			timeRemaining -= time.Since(t)
+			var action RoundAction
+			action, timeRemaining = hand.getPlayerAction(player, timeRemaining)
+			err := hand.PlayerAction(player, action)
 			if err == nil {
 				success = true
+				hand.notify(&Turn{Player: player,
+					Fold: action.actionType == Fold, Bet: player.BetAmount})
 			} else {
 				log.Println(err)
 			}
 		}
 		log.Println(player.Name, "made their bet")
+		if err := hand.audit("action by " + player.Name); err != nil {
+			return err
+		}
 	}
 	hand.createPots()
 	log.Println("Round of betting is done")
 	hand.Round.RoundDone = true
+	return hand.audit("createpots")
+}
+
+// notify the table's state listeners that the hand changed
+func (hand *Hand) notify(turn *Turn) {
+	if hand.stateChanged != nil {
+		hand.stateChanged(turn)
+	}
 }
 
-func getPlayerAction(ctx context.Context, player *Player) RoundAction {
+// getPlayerAction wait up to timeRemaining for the player's action, folding
+// if none is taken in time, and make the changes queued for the table
+// meanwhile. The clock stops while play is paused. A player that is
+// disconnected when it runs out may be given the disconnect timer. The time
+// the player has left is returned with the action.
+func (hand *Hand) getPlayerAction(player *Player,
+	timeRemaining time.Duration) (RoundAction, time.Duration) {
 	log.Println("Waiting for action from", player.Name)
-	action := RoundAction{actionType: Fold}
-	select {
-	case action = <-player.ActionChan:
-	case <-ctx.Done():
-		log.Println(player.Name, "timed out, folding")
+	protected := false
+	for {
+		paused, changed := hand.pause.state()
+		hand.clock = turnClock{player: player, remaining: timeRemaining}
+		if paused {
+			select {
+			case cmd := <-hand.commands:
+				cmd.run()
+			case <-changed:
+			}
+			continue
+		}
+		t := time.Now()
+		hand.clock.started = t
+		timer := time.NewTimer(timeRemaining)
+		select {
+		case action := <-player.ActionChan:
+			timer.Stop()
+			return action, timeRemaining - time.Since(t)
+		case <-timer.C:
+			if !protected && hand.protect(player) {
+				protected = true
+				timeRemaining = hand.TableConfig.disconnectProtection.Time
+				continue
+			}
+			log.Println(player.Name, "timed out")
+			return hand.timedOut(player, protected), 0
+		case cmd := <-hand.commands:
+			timer.Stop()
+			timeRemaining -= time.Since(t)
+			cmd.run()
+		case <-changed:
+			timer.Stop()
+			timeRemaining -= time.Since(t)
+		}
 	}
-	return action
 }
@@ -0,0 +1,344 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// DefaultSeatOfferTimeout how long a player on the waitlist has to accept a
+// seat offered to them unless the table's config says otherwise
+const DefaultSeatOfferTimeout = 30 * time.Second
+
+// ErrStandersFull the table already has MaxStandersSize players watching it
+var ErrStandersFull = errors.New("the table's standers are full")
+
+type (
+	// SeatOffer a free seat offered to a player on the waitlist, it is
+	// withdrawn and offered to the next player if not accepted before it
+	// expires
+	SeatOffer struct {
+		Player  *Player
+		Seat    int
+		Expires time.Time
+		timer   *time.Timer
+	}
+
+	// WaitlistListener is called when a seat offer expires, which happens
+	// outside of the goroutine playing the table
+	WaitlistListener func(table *Table)
+
+	// waitlistState the players waiting for a seat at a full Table, in the
+	// order they joined, and the seats offered to them
+	waitlistState struct {
+		waiting   []*Player
+		offers    []*SeatOffer
+		listeners []WaitlistListener
+	}
+)
+
+// WithSeatOfferTimeout a copy of the config that gives players on the
+// waitlist timeout to accept a seat offered to them
+func (config TableConfig) WithSeatOfferTimeout(
+	timeout time.Duration) TableConfig {
+	config.seatOfferTimeout = timeout
+	return config
+}
+
+func (config TableConfig) seatOfferTimeoutOrDefault() time.Duration {
+	if config.seatOfferTimeout > 0 {
+		return config.seatOfferTimeout
+	}
+	return DefaultSeatOfferTimeout
+}
+
+// AddWaitlistListener call listener whenever a seat offer expires
+func (table *Table) AddWaitlistListener(listener WaitlistListener) {
+	table.tableMutex.Lock()
+	defer table.tableMutex.Unlock()
+	table.waitlist.listeners = append(table.waitlist.listeners, listener)
+}
+
+// Watch the table as a stander, who is shown its state without anyone's hole
+// cards. Fails with ErrStandersFull once MaxStandersSize players are
+// watching.
+func (table *Table) Watch(player *Player) error {
+	return table.do(func() error {
+		if table.isSeated(player) {
+			return errors.New("watch: player is sitting at this table")
+		}
+		return table.watch(player)
+	})
+}
+
+func (table *Table) watch(player *Player) error {
+	free := -1
+	for i, p := range table.Standers {
+		if p == player {
+			return nil
+		} else if p == nil && free < 0 {
+			free = i
+		}
+	}
+	if free < 0 {
+		return fmt.Errorf("watch: %w", ErrStandersFull)
+	}
+	table.Standers[free] = player
+	return nil
+}
+
+// standAside a player that stood up from their seat watches the table, or
+// leaves it if the standers are full, and their seat is offered to the
+// waitlist
+func (table *Table) standAside(player *Player) {
+	if err := table.watch(player); err != nil {
+		log.Println(player.Name, "left the table,", err)
+	}
+	table.offerSeats()
+}
+
+// Watching if the player is watching the table as a stander
+func (table *Table) Watching(player *Player) bool {
+	table.tableMutex.RLock()
+	defer table.tableMutex.RUnlock()
+	return table.isStander(player)
+}
+
+func (table *Table) isStander(player *Player) bool {
+	for _, p := range table.Standers {
+		if p == player {
+			return true
+		}
+	}
+	return false
+}
+
+// StopWatching the player stops watching the table, leaving its waitlist and
+// giving up any seat offered to them
+func (table *Table) StopWatching(player *Player) error {
+	return table.do(func() error {
+		if !table.isStander(player) {
+			return errors.New("stopwatching: player is not watching this table")
+		}
+		table.leaveStanders(player)
+		table.offerSeats()
+		return nil
+	})
+}
+
+// leaveStanders remove the player from the standers and the waitlist, e.g.
+// when they sit down
+func (table *Table) leaveStanders(player *Player) {
+	for i, p := range table.Standers {
+		if p == player {
+			table.Standers[i] = nil
+		}
+	}
+	table.leaveWaitlist(player)
+}
+
+// JoinWaitlist wait for a seat at the full table, watching it meanwhile.
+// Seats are offered to waiting players in the order they joined as they
+// become free.
+func (table *Table) JoinWaitlist(player *Player) error {
+	return table.do(func() error {
+		if table.isSeated(player) {
+			return errors.New("joinwaitlist: player is sitting at this table")
+		} else if table.isWaiting(player) {
+			return errors.New("joinwaitlist: player is already waiting")
+		} else if table.freeSeat() >= 0 {
+			return errors.New("joinwaitlist: table has a free seat")
+		} else if err := table.watch(player); err != nil {
+			return fmt.Errorf("joinwaitlist: %w", err)
+		}
+		table.waitlist.waiting = append(table.waitlist.waiting, player)
+		return nil
+	})
+}
+
+// LeaveWaitlist stop waiting for a seat, giving up any seat offered to the
+// player, who keeps watching the table
+func (table *Table) LeaveWaitlist(player *Player) error {
+	return table.do(func() error {
+		if !table.isWaiting(player) {
+			return errors.New("leavewaitlist: player is not waiting")
+		}
+		table.leaveWaitlist(player)
+		table.offerSeats()
+		return nil
+	})
+}
+
+func (table *Table) leaveWaitlist(player *Player) {
+	waiting := table.waitlist.waiting[:0]
+	for _, p := range table.waitlist.waiting {
+		if p != player {
+			waiting = append(waiting, p)
+		}
+	}
+	table.waitlist.waiting = waiting
+	if offer := table.offerFor(player); offer != nil {
+		table.withdrawOffer(offer)
+	}
+}
+
+func (table *Table) isWaiting(player *Player) bool {
+	for _, p := range table.waitlist.waiting {
+		if p == player {
+			return true
+		}
+	}
+	return false
+}
+
+// AcceptSeat sit the player at the seat offered to them, buying in for buyIn
+// if the table has a bank. The seat stays offered to them if they cannot sit.
+func (table *Table) AcceptSeat(player *Player, buyIn int) (int, error) {
+	var seat int
+	err := table.do(func() error {
+		offer := table.offerFor(player)
+		if offer == nil {
+			return errors.New("acceptseat: no seat is offered to the player")
+		} else if table.Bank != nil && player.Funds != 0 {
+			return errors.New("acceptseat: player already has chips")
+		}
+		seat = offer.Seat
+		table.removeOffer(offer)
+		if err := table.sitDownWithBuyIn(player, seat, buyIn); err != nil {
+			table.waitlist.offers = append(table.waitlist.offers, offer)
+			return fmt.Errorf("acceptseat: %w", err)
+		}
+		offer.timer.Stop()
+		return nil
+	})
+	return seat, err
+}
+
+// sitDownWithBuyIn sit the player down, first withdrawing buyIn from the
+// bank if the table has one
+func (table *Table) sitDownWithBuyIn(player *Player, seat int,
+	buyIn int) error {
+	if table.Bank == nil {
+		return table.sitDown(player, seat)
+	}
+	return table.buyIn(player, seat, buyIn)
+}
+
+// DeclineSeat turn down the seat offered to the player, who leaves the
+// waitlist and the seat is offered to the next player waiting
+func (table *Table) DeclineSeat(player *Player) error {
+	return table.do(func() error {
+		if table.offerFor(player) == nil {
+			return errors.New("declineseat: no seat is offered to the player")
+		}
+		table.leaveWaitlist(player)
+		table.offerSeats()
+		return nil
+	})
+}
+
+// offerSeats offer each free seat not already offered to the next waiting
+// player without an offer. Called holding tableMutex whenever a seat may have
+// been freed or a waiting player given up their offer.
+func (table *Table) offerSeats() {
+	for seat, p := range table.Players {
+		if p != nil || table.seatOffered(seat) ||
+			table.seatRequestedBy(seat) != nil {
+			continue
+		}
+		player := table.nextWaiting()
+		if player == nil {
+			return
+		}
+		timeout := table.TableConfig.seatOfferTimeoutOrDefault()
+		offer := &SeatOffer{
+			Player: player, Seat: seat, Expires: time.Now().Add(timeout),
+		}
+		offer.timer = time.AfterFunc(timeout, func() {
+			table.expireOffer(offer)
+		})
+		table.waitlist.offers = append(table.waitlist.offers, offer)
+	}
+}
+
+// nextWaiting the first player on the waitlist without a seat offer
+func (table *Table) nextWaiting() *Player {
+	for _, p := range table.waitlist.waiting {
+		if table.offerFor(p) == nil {
+			return p
+		}
+	}
+	return nil
+}
+
+// expireOffer withdraw an offer that was not accepted in time, the player
+// loses their place on the waitlist
+func (table *Table) expireOffer(offer *SeatOffer) {
+	expired := false
+	table.do(func() error {
+		if table.offerFor(offer.Player) == offer {
+			expired = true
+			table.leaveWaitlist(offer.Player)
+			table.offerSeats()
+		}
+		return nil
+	})
+	if !expired {
+		return
+	}
+	table.tableMutex.RLock()
+	listeners := table.waitlist.listeners
+	table.tableMutex.RUnlock()
+	for _, listener := range listeners {
+		listener(table)
+	}
+}
+
+func (table *Table) offerFor(player *Player) *SeatOffer {
+	for _, offer := range table.waitlist.offers {
+		if offer.Player == player {
+			return offer
+		}
+	}
+	return nil
+}
+
+func (table *Table) seatOffered(seat int) bool {
+	return table.seatOfferedTo(seat) != nil
+}
+
+// seatOfferedTo the player seat is offered to, nil if it is not offered
+func (table *Table) seatOfferedTo(seat int) *Player {
+	for _, offer := range table.waitlist.offers {
+		if offer.Seat == seat {
+			return offer.Player
+		}
+	}
+	return nil
+}
+
+func (table *Table) withdrawOffer(offer *SeatOffer) {
+	offer.timer.Stop()
+	table.removeOffer(offer)
+}
+
+func (table *Table) removeOffer(offer *SeatOffer) {
+	offers := table.waitlist.offers[:0]
+	for _, o := range table.waitlist.offers {
+		if o != offer {
+			offers = append(offers, o)
+		}
+	}
+	table.waitlist.offers = offers
+}
+
+// freeSeat the first seat that is empty and not offered to a waiting player
+// or requested for a seat change, -1 if there is none
+func (table *Table) freeSeat() int {
+	seat, err := table.chooseSeat(AnySeat)
+	if err != nil {
+		return -1
+	}
+	return seat
+}
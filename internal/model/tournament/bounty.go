@@ -0,0 +1,76 @@
+package tournament
+
+import (
+	"log"
+
+	model "github.com/ekotlikoff/gopoker/internal/model/table"
+)
+
+// addBounty put amount on the player's head as they enter the tournament
+func (s *standings) addBounty(player *model.Player, amount int) {
+	if amount == 0 {
+		return
+	}
+	if s.bounties == nil {
+		s.bounties = make(map[*model.Player]int)
+		s.bountiesWon = make(map[*model.Player]int)
+	}
+	s.bounties[player] = amount
+}
+
+// collectBounties award the bounty on the head of each eliminated player to
+// the players that eliminated them, split evenly with any remainder to the
+// first. In progressive knockouts only half is paid and the other half is
+// added to the eliminators' own bounties.
+func (s *standings) collectBounties(bank model.Bank,
+	eliminations []model.Elimination, progressive bool) {
+	for _, elimination := range eliminations {
+		bounty := s.bounties[elimination.Player]
+		if bounty == 0 || len(elimination.By) == 0 {
+			continue
+		}
+		delete(s.bounties, elimination.Player)
+		paid := bounty
+		if progressive {
+			paid = bounty / 2
+		}
+		for i, player := range elimination.By {
+			share, headShare := paid/len(elimination.By),
+				(bounty-paid)/len(elimination.By)
+			if i == 0 {
+				share += paid % len(elimination.By)
+				headShare += (bounty - paid) % len(elimination.By)
+			}
+			s.bounties[player] += headShare
+			s.payBounty(bank, player, share)
+			log.Println(player.Name, "won a bounty of", share, "for eliminating",
+				elimination.Player.Name)
+		}
+	}
+}
+
+// collectOwnBounty pay the player the bounty on their own head, e.g. once they
+// have won the tournament
+func (s *standings) collectOwnBounty(bank model.Bank, player *model.Player) {
+	bounty := s.bounties[player]
+	if bounty == 0 {
+		return
+	}
+	delete(s.bounties, player)
+	s.payBounty(bank, player, bounty)
+}
+
+func (s *standings) payBounty(bank model.Bank, player *model.Player,
+	amount int) {
+	s.bountiesWon[player] += amount
+	if amount > 0 && bank != nil {
+		if err := bank.Deposit(player.Name, amount); err != nil {
+			log.Println("ERROR failed to pay bounty to", player.Name, err)
+		}
+	}
+}
+
+// bounty on the player's head
+func (s *standings) bounty(player *model.Player) int {
+	return s.bounties[player]
+}
@@ -0,0 +1,723 @@
+package tournament
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ekotlikoff/gopoker/internal/model/payouts"
+	model "github.com/ekotlikoff/gopoker/internal/model/table"
+)
+
+type (
+	// MultiTableConfig define the structure of a MultiTable tournament
+	MultiTableConfig struct {
+		// TableSize the number of players seated at each table, and at the
+		// final table
+		TableSize int
+		// BuyIn each entrant pays into the prize pool
+		BuyIn int
+		// StartingStack of chips each entrant starts with
+		StartingStack int
+		// Blinds schedule of every table, it must not be empty. Levels that
+		// last a Duration keep the tables' blinds in step.
+		Blinds []model.BlindLevel
+		// Payouts the percentage of the prize pool paid to each finishing
+		// place, e.g. {50, 30, 20} pays the top three
+		Payouts []int
+		// Bounty the part of BuyIn put on each entrant's head, paid to the
+		// player that eliminates them rather than into the prize pool
+		Bounty int
+		// Progressive knockout, only half of a bounty is paid and the other
+		// half is added to the eliminator's own bounty
+		Progressive bool
+		// LateRegistration registration stays open until a table reaches
+		// this level of Blinds, zero closes it when the tournament starts
+		LateRegistration int
+		// ReEntries a busted player can buy in again while registration is
+		// open
+		ReEntries int
+		// AddOn chips a player can buy once for AddOnCost during the first
+		// break of Blinds, zero for no add on
+		AddOn     int
+		AddOnCost int
+		// TimeToBet each player has to act on their turn
+		TimeToBet time.Duration
+		// TimeBetweenHands to pause after each hand
+		TimeBetweenHands time.Duration
+	}
+
+	// MultiTable is a tournament director running many tables. Players are
+	// seated randomly when it starts, moved between tables to keep them
+	// balanced as players are eliminated, and tables are broken until the
+	// remaining players meet at the final table. On the bubble, and while
+	// the final table is forming, every table plays hand for hand.
+	MultiTable struct {
+		Config MultiTableConfig
+		// Bank buy ins are withdrawn from and payouts deposited to, if nil
+		// no chips change hands outside the tournament
+		Bank   model.Bank
+		state  State
+		tables []*mtTable
+		// tableConfig of every table the tournament opens
+		tableConfig model.TableConfig
+		// prizePool of the buy ins, re-entries and add ons, less bounties
+		prizePool int
+		// lateRegistration is open, busted players are placed once it
+		// closes
+		lateRegistration bool
+		// reEntries and addOns bought by each player
+		reEntries map[string]int
+		addOns    map[string]bool
+		done      chan struct{}
+		mtMutex   sync.Mutex
+		// playing the number of tables whose Play is running
+		playing int
+		// handForHand tables wait here until every playing table has
+		// finished its hand
+		handForHand *sync.Cond
+		arrived     int
+		round       int
+		// pending eliminations waiting for the hand for hand round to end
+		pending       []*model.Player
+		pendingStacks map[*model.Player]int
+		standings
+	}
+
+	// mtTable is one table of a MultiTable and the players the director has
+	// seated there that are still in the tournament
+	mtTable struct {
+		table   *model.Table
+		players []*model.Player
+		broken  bool
+		// stopped while it has too few players to deal a hand
+		stopped bool
+		// wake is signalled when players are moved to the table
+		wake chan struct{}
+	}
+)
+
+// NewMultiTable create a multi table tournament that is open for registration
+func NewMultiTable(config MultiTableConfig) (*MultiTable, error) {
+	if config.TableSize < model.MinPlayersToPlay ||
+		config.TableSize > model.MaxTableSize {
+		return nil, fmt.Errorf("newmultitable: table size must be between %d and %d",
+			model.MinPlayersToPlay, model.MaxTableSize)
+	} else if config.StartingStack <= 0 || config.BuyIn < 0 {
+		return nil, errors.New("newmultitable: invalid buy in or starting stack")
+	} else if len(config.Payouts) == 0 {
+		return nil, errors.New("newmultitable: payouts must not be empty")
+	} else if config.Bounty < 0 || config.Bounty > config.BuyIn {
+		return nil, errors.New("newmultitable: bounty must be part of the buy in")
+	} else if config.LateRegistration < 0 || config.ReEntries < 0 ||
+		config.AddOn < 0 || config.AddOnCost < 0 {
+		return nil, errors.New("newmultitable: invalid late registration, " +
+			"re-entries or add on")
+	} else if config.AddOn > 0 && firstBreak(config.Blinds) < 0 {
+		return nil, errors.New("newmultitable: an add on needs a break")
+	}
+	tableConfig, err := model.NewTableConfig(0, config.TimeToBet,
+		config.TimeBetweenHands).WithShortStackBlinds().
+		WithBlindSchedule(config.Blinds)
+	if err != nil {
+		return nil, fmt.Errorf("newmultitable: %w", err)
+	}
+	mt := &MultiTable{
+		Config: config, tableConfig: tableConfig, state: Registering,
+		done: make(chan struct{}), pendingStacks: make(map[*model.Player]int),
+		reEntries: make(map[string]int), addOns: make(map[string]bool),
+	}
+	mt.handForHand = sync.NewCond(&mt.mtMutex)
+	return mt, nil
+}
+
+func firstBreak(levels []model.BlindLevel) int {
+	for i, level := range levels {
+		if level.Break {
+			return i
+		}
+	}
+	return -1
+}
+
+// State of the tournament
+func (mt *MultiTable) State() State {
+	mt.mtMutex.Lock()
+	defer mt.mtMutex.Unlock()
+	return mt.state
+}
+
+// Done is closed once the tournament is finished
+func (mt *MultiTable) Done() <-chan struct{} {
+	return mt.done
+}
+
+// PrizePool the sum of the entrants' buy ins, re-entries and add ons, less
+// their bounties
+func (mt *MultiTable) PrizePool() int {
+	mt.mtMutex.Lock()
+	defer mt.mtMutex.Unlock()
+	return mt.prizePool
+}
+
+// Bounty on the player's head
+func (mt *MultiTable) Bounty(player *model.Player) int {
+	mt.mtMutex.Lock()
+	defer mt.mtMutex.Unlock()
+	return mt.bounty(player)
+}
+
+// Tables the tables that have not been broken
+func (mt *MultiTable) Tables() []*model.Table {
+	mt.mtMutex.Lock()
+	defer mt.mtMutex.Unlock()
+	tables := []*model.Table{}
+	for _, t := range mt.active() {
+		tables = append(tables, t.table)
+	}
+	return tables
+}
+
+// Register the player, they are seated when the tournament starts or, during
+// late registration, at the table with the fewest players
+func (mt *MultiTable) Register(player *model.Player) error {
+	mt.mtMutex.Lock()
+	defer mt.mtMutex.Unlock()
+	if mt.state != Registering && !mt.updateLateRegistration() {
+		return errors.New("register: registration is closed")
+	}
+	if mt.isRegistered(player.Name) {
+		return errors.New("register: player is already registered")
+	}
+	if mt.Bank != nil {
+		if err := mt.Bank.Withdraw(player.Name, mt.Config.BuyIn); err != nil {
+			return fmt.Errorf("register: %w", err)
+		}
+	}
+	mt.prizePool += mt.Config.BuyIn - mt.Config.Bounty
+	mt.registered = append(mt.registered, player)
+	mt.addBounty(player, mt.Config.Bounty)
+	log.Println(player.Name, "registered,", len(mt.registered), "entrants")
+	if mt.state == Running {
+		player.Funds = mt.Config.StartingStack
+		mt.seatLate(player)
+	}
+	return nil
+}
+
+// ReEnter buy the busted player back in with the starting stack, only while
+// registration is open and they have re-entries left
+func (mt *MultiTable) ReEnter(player *model.Player) error {
+	mt.mtMutex.Lock()
+	defer mt.mtMutex.Unlock()
+	if mt.state != Running || !mt.updateLateRegistration() {
+		return errors.New("reenter: registration is closed")
+	} else if mt.reEntries[player.Name] >= mt.Config.ReEntries {
+		return errors.New("reenter: player has no re-entries left")
+	}
+	busted := -1
+	for i, p := range mt.busted {
+		if p == player {
+			busted = i
+		}
+	}
+	if busted < 0 {
+		return errors.New("reenter: player is not busted")
+	}
+	if mt.Bank != nil {
+		if err := mt.Bank.Withdraw(player.Name, mt.Config.BuyIn); err != nil {
+			return fmt.Errorf("reenter: %w", err)
+		}
+	}
+	mt.busted = append(mt.busted[:busted], mt.busted[busted+1:]...)
+	mt.reEntries[player.Name]++
+	mt.prizePool += mt.Config.BuyIn - mt.Config.Bounty
+	mt.addBounty(player, mt.Config.Bounty)
+	log.Println(player.Name, "re-entered")
+	player.Funds = mt.Config.StartingStack
+	mt.seatLate(player)
+	return nil
+}
+
+// AddOn buy the player the add on chips, once and only while their table is
+// on the first break. The chips are added once the break is over.
+func (mt *MultiTable) AddOn(player *model.Player) error {
+	mt.mtMutex.Lock()
+	defer mt.mtMutex.Unlock()
+	if mt.state != Running || mt.Config.AddOn == 0 {
+		return errors.New("addon: there is no add on")
+	} else if mt.addOns[player.Name] {
+		return errors.New("addon: player has already taken the add on")
+	}
+	var table *model.Table
+	for _, t := range mt.active() {
+		for _, p := range t.players {
+			if p == player {
+				table = t.table
+			}
+		}
+	}
+	if table == nil {
+		return errors.New("addon: player is not in the tournament")
+	} else if info := table.BlindInfo(); info.Level != firstBreak(mt.Config.Blinds) {
+		return errors.New("addon: the add on is only offered at the first break")
+	}
+	if mt.Bank != nil {
+		if err := mt.Bank.Withdraw(player.Name, mt.Config.AddOnCost); err != nil {
+			return fmt.Errorf("addon: %w", err)
+		}
+	}
+	if err := table.AddChips(player, mt.Config.AddOn); err != nil {
+		if mt.Bank != nil {
+			mt.Bank.Deposit(player.Name, mt.Config.AddOnCost)
+		}
+		return fmt.Errorf("addon: %w", err)
+	}
+	mt.addOns[player.Name] = true
+	mt.prizePool += mt.Config.AddOnCost
+	log.Println(player.Name, "took the add on")
+	return nil
+}
+
+// updateLateRegistration close late registration once a table reaches the
+// configured level, or only one player has chips, placing the busted players.
+// Returns whether it is still open.
+func (mt *MultiTable) updateLateRegistration() bool {
+	if !mt.lateRegistration {
+		return false
+	}
+	closed := len(mt.remaining()) < model.MinPlayersToPlay
+	for _, t := range mt.active() {
+		closed = closed || t.table.BlindInfo().Level >= mt.Config.LateRegistration
+	}
+	if closed {
+		log.Println("Late registration is closed")
+		mt.lateRegistration = false
+		mt.placeBusted()
+	}
+	return !closed
+}
+
+// seatLate seat the player at the table with the fewest players, opening a
+// table if every table is full
+func (mt *MultiTable) seatLate(player *model.Player) {
+	active := mt.active()
+	shortest := active[len(active)-1]
+	if len(shortest.players) < mt.Config.TableSize {
+		mt.seat(player, shortest)
+		return
+	}
+	t := mt.openTable()
+	t.table.SyncBlinds(active[0].table)
+	mt.seat(player, t)
+	mt.playing++
+	go mt.run(t)
+}
+
+func (mt *MultiTable) openTable() *mtTable {
+	t := &mtTable{
+		table: model.NewTableWithConfig(mt.tableConfig),
+		wake:  make(chan struct{}, 1),
+	}
+	t.table.AddHandListener(mt.handListener(t))
+	mt.tables = append(mt.tables, t)
+	return t
+}
+
+// Unregister the player and refund their buy in, only while registering
+func (mt *MultiTable) Unregister(player *model.Player) error {
+	mt.mtMutex.Lock()
+	defer mt.mtMutex.Unlock()
+	if mt.state != Registering {
+		return errors.New("unregister: the tournament has started")
+	}
+	if !mt.unregister(player) {
+		return errors.New("unregister: player is not registered")
+	}
+	mt.prizePool -= mt.Config.BuyIn - mt.Config.Bounty
+	delete(mt.bounties, player)
+	refund(mt.Bank, player, mt.Config.BuyIn)
+	return nil
+}
+
+// Start close registration, unless it is open late, seat the entrants randomly across as few tables
+// as will hold them and start play at every table
+func (mt *MultiTable) Start() error {
+	mt.mtMutex.Lock()
+	defer mt.mtMutex.Unlock()
+	if mt.state != Registering {
+		return errors.New("start: the tournament has already started")
+	} else if len(mt.registered) < model.MinPlayersToPlay {
+		return errors.New("start: not enough entrants")
+	} else if err := validPayouts(mt.Config.Payouts, len(mt.registered)); err != nil {
+		return fmt.Errorf("start: %w", err)
+	}
+	tableCount := (len(mt.registered) + mt.Config.TableSize - 1) /
+		mt.Config.TableSize
+	for i := 0; i < tableCount; i++ {
+		mt.openTable()
+	}
+	entrants := append([]*model.Player{}, mt.registered...)
+	rand.Shuffle(len(entrants), func(i, j int) {
+		entrants[i], entrants[j] = entrants[j], entrants[i]
+	})
+	for i, player := range entrants {
+		player.Funds = mt.Config.StartingStack
+		mt.seat(player, mt.tables[i%tableCount])
+	}
+	mt.state = Running
+	mt.lateRegistration = mt.Config.LateRegistration > 0
+	log.Println("Tournament is starting with", len(entrants), "entrants at",
+		tableCount, "tables")
+	for _, t := range mt.tables {
+		mt.playing++
+		go mt.run(t)
+	}
+	return nil
+}
+
+// run play the table until it is broken or the tournament is finished. A
+// table left with too few players to deal waits for players to be moved to it.
+func (mt *MultiTable) run(t *mtTable) {
+	for {
+		err := t.table.Play()
+		mt.mtMutex.Lock()
+		mt.playing--
+		t.stopped = true
+		if mt.arrived > 0 && mt.arrived >= mt.playing {
+			mt.endHandForHand()
+		}
+		if !t.broken && mt.state != Finished {
+			log.Println("Tournament table stopped,", err)
+			mt.balance(t)
+		}
+		if t.broken || mt.state == Finished {
+			mt.mtMutex.Unlock()
+			return
+		}
+		mt.mtMutex.Unlock()
+		<-t.wake
+		mt.mtMutex.Lock()
+		if t.broken || mt.state == Finished {
+			mt.mtMutex.Unlock()
+			return
+		}
+		mt.playing++
+		t.stopped = false
+		mt.mtMutex.Unlock()
+	}
+}
+
+// handListener is a model.HandListener recording the players t lost in the
+// hand and balancing the tables, hand for hand it waits for every table to
+// finish its hand first
+func (mt *MultiTable) handListener(t *mtTable) model.HandListener {
+	return func(table *model.Table, result *model.HandResult) {
+		mt.mtMutex.Lock()
+		defer mt.mtMutex.Unlock()
+		if mt.state == Finished {
+			return
+		}
+		mt.cancelDeal()
+		mt.collectBounties(mt.Bank, result.Eliminations, mt.Config.Progressive)
+		stacks := startingStacks(result)
+		eliminated := []*model.Player{}
+		for player := range result.Net {
+			if player.Funds == 0 {
+				t.remove(player)
+				table.Unseat(player)
+				eliminated = append(eliminated, player)
+			}
+		}
+		if mt.lateRegistration {
+			sort.Slice(eliminated, func(i, j int) bool {
+				return stacks[eliminated[i]] < stacks[eliminated[j]]
+			})
+			mt.busted = append(mt.busted, eliminated...)
+		} else {
+			for _, player := range eliminated {
+				mt.pending = append(mt.pending, player)
+				mt.pendingStacks[player] = stacks[player]
+			}
+		}
+		mt.updateLateRegistration()
+		if !mt.isHandForHand() {
+			mt.settle()
+			if mt.state != Finished {
+				mt.balance(t)
+			}
+			return
+		}
+		mt.arrived++
+		if mt.arrived < mt.playing {
+			round := mt.round
+			for round == mt.round {
+				mt.handForHand.Wait()
+			}
+			return
+		}
+		mt.endHandForHand()
+	}
+}
+
+// isHandForHand while more than one table is left and the remaining players
+// are on the bubble or will fit at the final table
+func (mt *MultiTable) isHandForHand() bool {
+	remaining := len(mt.remaining()) - len(mt.pending)
+	paid := len(mt.Config.Payouts)
+	return len(mt.active()) > 1 && (remaining <= mt.Config.TableSize ||
+		remaining == paid+1)
+}
+
+// endHandForHand every playing table has finished its hand, place the players
+// eliminated in the round and balance the tables, or form the final table,
+// before releasing them
+func (mt *MultiTable) endHandForHand() {
+	mt.arrived = 0
+	mt.round++
+	defer mt.handForHand.Broadcast()
+	mt.settle()
+	if mt.state == Finished {
+		return
+	}
+	if len(mt.remaining()) <= mt.Config.TableSize {
+		mt.formFinalTable()
+		return
+	}
+	for _, t := range mt.active() {
+		mt.balance(t)
+	}
+}
+
+// settle place the pending eliminations and pay out once one player remains
+func (mt *MultiTable) settle() {
+	mt.eliminate(mt.pending, mt.pendingStacks)
+	mt.pending = nil
+	mt.pendingStacks = make(map[*model.Player]int)
+	remaining := mt.remaining()
+	if len(remaining) != 1 || mt.state == Finished {
+		return
+	}
+	mt.finish(remaining[0])
+	remaining[0].StandUp()
+	mt.collectOwnBounty(mt.Bank, remaining[0])
+	mt.pay(mt.Bank, mt.prizePool, mt.Config.Payouts)
+	mt.end()
+}
+
+// end the tournament, releasing any tables waiting hand for hand or for
+// players so they stop
+func (mt *MultiTable) end() {
+	mt.state = Finished
+	close(mt.done)
+	mt.arrived = 0
+	mt.round++
+	mt.handForHand.Broadcast()
+	for _, t := range mt.tables {
+		mt.wake(t)
+	}
+}
+
+// balance break a table if the remaining players fit at fewer tables, or
+// move players from t until it has at most one more than the shortest table.
+// Only t and stopped tables are between hands, so only they are broken.
+func (mt *MultiTable) balance(t *mtTable) {
+	active := mt.active()
+	if len(active) < 2 {
+		return
+	}
+	shortest := len(active[len(active)-1].players)
+	if len(mt.remaining()) <= (len(active)-1)*mt.Config.TableSize {
+		for _, other := range active {
+			if (other == t || other.stopped) && len(other.players) == shortest {
+				mt.breakTable(other)
+				return
+			}
+		}
+	}
+	for len(t.players) > shortest+1 {
+		mt.move(t, t.nextBigBlind())
+		active = mt.active()
+		shortest = len(active[len(active)-1].players)
+	}
+}
+
+// breakTable move every player from t to the tables with the fewest players
+func (mt *MultiTable) breakTable(t *mtTable) {
+	log.Println("Breaking a table with", len(t.players), "players")
+	t.broken = true
+	for len(t.players) > 0 {
+		mt.move(t, t.players[0])
+	}
+	mt.wake(t)
+}
+
+// move the player from t to the table with the fewest players
+func (mt *MultiTable) move(from *mtTable, player *model.Player) {
+	var to *mtTable
+	for _, t := range mt.active() {
+		if t != from && (to == nil || len(t.players) < len(to.players)) {
+			to = t
+		}
+	}
+	from.table.Unseat(player)
+	from.remove(player)
+	log.Println("Moving", player.Name, "to another table")
+	mt.seat(player, to)
+}
+
+// formFinalTable move every remaining player to a random seat at one table
+// and break the others, only called while every table is between hands
+func (mt *MultiTable) formFinalTable() {
+	active := mt.active()
+	final := active[0]
+	players := []*model.Player{}
+	for _, t := range active {
+		for _, player := range t.players {
+			t.table.Unseat(player)
+			players = append(players, player)
+		}
+		t.players = nil
+		if t != final {
+			t.broken = true
+			mt.wake(t)
+		}
+	}
+	log.Println("Final table is formed with", len(players), "players")
+	for _, player := range players {
+		mt.seat(player, final)
+	}
+}
+
+// seat the player at a random empty seat of t
+func (mt *MultiTable) seat(player *model.Player, t *mtTable) {
+	_, err := t.table.SeatInFirstEmpty(player, rand.Perm(mt.Config.TableSize))
+	if err != nil {
+		log.Println("ERROR no empty seat for", player.Name, err)
+		return
+	}
+	t.players = append(t.players, player)
+	mt.wake(t)
+}
+
+func (mt *MultiTable) wake(t *mtTable) {
+	select {
+	case t.wake <- struct{}{}:
+	default:
+	}
+}
+
+// active the tables that have not been broken
+func (mt *MultiTable) active() []*mtTable {
+	active := []*mtTable{}
+	for _, t := range mt.tables {
+		if !t.broken {
+			active = append(active, t)
+		}
+	}
+	sort.SliceStable(active, func(i, j int) bool {
+		return len(active[i].players) > len(active[j].players)
+	})
+	return active
+}
+
+// ProposeDeal to the remaining players dividing the prizes left between them
+// by their stacks, only between hands at every table. It is cancelled if a
+// hand finishes before every player accepts.
+func (mt *MultiTable) ProposeDeal(kind payouts.DealKind) (*payouts.Deal, error) {
+	mt.mtMutex.Lock()
+	defer mt.mtMutex.Unlock()
+	if mt.state != Running {
+		return nil, errors.New("proposedeal: the tournament is not running")
+	} else if mt.lateRegistration {
+		return nil, errors.New("proposedeal: registration is still open")
+	} else if len(mt.pending) > 0 {
+		return nil, errors.New("proposedeal: players are waiting to be placed " +
+			"at the end of the hand for hand round")
+	}
+	stacks, err := mt.stacks()
+	if err != nil {
+		return nil, fmt.Errorf("proposedeal: %w", err)
+	}
+	deal, err := mt.proposeDeal(kind, mt.prizePool, mt.Config.Payouts, stacks)
+	if err != nil {
+		return nil, fmt.Errorf("proposedeal: %w", err)
+	}
+	return deal, nil
+}
+
+// stacks of the players at every active table, errMidHand if any is playing
+// a hand. Called holding mtMutex.
+func (mt *MultiTable) stacks() (map[*model.Player]int, error) {
+	stacks := make(map[*model.Player]int)
+	for _, t := range mt.active() {
+		tableStacks, ok := t.table.Stacks()
+		if !ok {
+			return nil, errMidHand
+		}
+		for player, stack := range tableStacks {
+			stacks[player] = stack
+		}
+	}
+	return stacks, nil
+}
+
+// AcceptDeal on behalf of the player, only between hands at every table. The
+// tournament is finished once every remaining player has accepted.
+func (mt *MultiTable) AcceptDeal(player *model.Player) error {
+	mt.mtMutex.Lock()
+	defer mt.mtMutex.Unlock()
+	if mt.state != Running {
+		return errors.New("acceptdeal: the tournament is not running")
+	} else if _, err := mt.stacks(); err != nil {
+		return fmt.Errorf("acceptdeal: %w", err)
+	}
+	agreed, err := mt.acceptDeal(mt.Bank, player, mt.prizePool,
+		mt.Config.Payouts)
+	if err != nil {
+		return fmt.Errorf("acceptdeal: %w", err)
+	}
+	if agreed {
+		mt.end()
+	}
+	return nil
+}
+
+// Results the finishing places decided so far, best place first
+func (mt *MultiTable) Results() []Finish {
+	mt.mtMutex.Lock()
+	defer mt.mtMutex.Unlock()
+	return mt.results()
+}
+
+func (t *mtTable) remove(player *model.Player) {
+	for i, p := range t.players {
+		if p == player {
+			t.players = append(t.players[:i], t.players[i+1:]...)
+			return
+		}
+	}
+}
+
+// nextBigBlind the player at t due to post the next big blind, moving them
+// means nobody skips or pays the blinds twice
+func (t *mtTable) nextBigBlind() *model.Player {
+	var next *model.Player
+	found := 0
+	for _, player := range t.table.SeatedFromDealer() {
+		if found == 3 {
+			break
+		}
+		for _, p := range t.players {
+			if p == player {
+				next = player
+				found++
+			}
+		}
+	}
+	return next
+}
@@ -0,0 +1,163 @@
+package tournament
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	model "github.com/ekotlikoff/gopoker/internal/model/table"
+)
+
+func TestMultiTable(t *testing.T) {
+	levels := []model.BlindLevel{}
+	for bb := 100; bb <= 6400; bb *= 2 {
+		levels = append(levels,
+			model.BlindLevel{SmallBlind: bb / 2, BigBlind: bb, Hands: 1})
+	}
+	mt, err := NewMultiTable(MultiTableConfig{
+		TableSize: 3, BuyIn: 100, StartingStack: 1000, Blinds: levels,
+		Payouts: []int{50, 30, 20}, TimeToBet: time.Millisecond * 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bank := model.NewMemoryBank()
+	mt.Bank = bank
+	for i := 0; i < 8; i++ {
+		player := model.NewPlayer(fmt.Sprint("Player", i))
+		bank.Deposit(player.Name, 100)
+		if err := mt.Register(player); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mt.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if len(mt.Tables()) != 3 {
+		t.Error("expected 8 entrants to be seated at 3 tables got",
+			len(mt.Tables()))
+	}
+	if err := mt.Register(model.NewPlayer("Late")); err == nil {
+		t.Error("expected registration to be closed once started")
+	}
+	select {
+	case <-mt.Done():
+	case <-time.After(time.Second * 10):
+		t.Fatal("tournament did not finish")
+	}
+	results := mt.Results()
+	if len(results) != 8 {
+		t.Fatal("expected 8 finishes got", results)
+	}
+	total := 0
+	for i, finish := range results {
+		if finish.Place != i+1 {
+			t.Error("expected place", i+1, "got", finish.Place)
+		}
+		total += bank.Balance(finish.Player.Name)
+	}
+	if results[0].Payout != 400 || results[1].Payout != 240 ||
+		results[2].Payout != 160 {
+		t.Error("expected payouts of 400, 240 and 160 got", results)
+	}
+	if total != 800 {
+		t.Error("expected the prize pool of 800 to be paid out got", total)
+	}
+}
+
+func TestMultiTableLateRegistration(t *testing.T) {
+	mt, err := NewMultiTable(MultiTableConfig{
+		TableSize: 3, BuyIn: 100, StartingStack: 100,
+		Blinds: []model.BlindLevel{
+			{SmallBlind: 100, BigBlind: 200, Hands: 1000},
+			{SmallBlind: 200, BigBlind: 400, Hands: 1000},
+		},
+		Payouts: []int{100}, TimeToBet: time.Millisecond,
+		TimeBetweenHands: time.Millisecond * 50, LateRegistration: 1,
+		ReEntries: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	players := []*model.Player{
+		model.NewPlayer("Leto"), model.NewPlayer("Paul"), model.NewPlayer("Jessica"),
+	}
+	for _, p := range players[:2] {
+		if err := mt.Register(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mt.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := mt.Register(players[2]); err != nil {
+		t.Fatal("expected late registration to be open", err)
+	}
+	if err := mt.ReEnter(players[0]); err == nil {
+		t.Error("expected a player that has not busted not to re-enter")
+	}
+	reEntered := false
+	for retries := 0; retries < 1000 && !reEntered; retries++ {
+		for _, p := range players {
+			if mt.ReEnter(p) == nil {
+				reEntered = true
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if !reEntered {
+		t.Fatal("expected a busted player to re-enter")
+	}
+	select {
+	case <-mt.Done():
+	case <-time.After(time.Second * 10):
+		t.Fatal("tournament did not finish")
+	}
+	if mt.PrizePool() != 400 {
+		t.Error("expected 3 buy ins and a re-entry in the prize pool got",
+			mt.PrizePool())
+	}
+	results := mt.Results()
+	if len(results) != 3 || results[0].Payout != 400 {
+		t.Error("expected 3 places with the winner taking 400 got", results)
+	}
+}
+
+func TestMultiTableAddOn(t *testing.T) {
+	mt, err := NewMultiTable(MultiTableConfig{
+		TableSize: 2, BuyIn: 100, StartingStack: 10000,
+		Blinds: []model.BlindLevel{
+			{SmallBlind: 50, BigBlind: 100, Hands: 1},
+			{Break: true, Duration: time.Second},
+			{SmallBlind: 100, BigBlind: 200, Hands: 1},
+		},
+		Payouts: []int{100}, TimeToBet: time.Millisecond, AddOn: 5000,
+		AddOnCost: 50,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	players := []*model.Player{model.NewPlayer("Leto"), model.NewPlayer("Paul")}
+	for _, p := range players {
+		mt.Register(p)
+	}
+	if err := mt.Start(); err != nil {
+		t.Fatal(err)
+	}
+	var addOnErr error
+	for retries := 0; retries < 500; retries++ {
+		if addOnErr = mt.AddOn(players[0]); addOnErr == nil {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if addOnErr != nil {
+		t.Fatal("expected the add on to be offered at the break", addOnErr)
+	}
+	if err := mt.AddOn(players[0]); err == nil {
+		t.Error("expected a second add on to fail")
+	}
+	if mt.PrizePool() != 250 {
+		t.Error("expected the add on in the prize pool got", mt.PrizePool())
+	}
+}
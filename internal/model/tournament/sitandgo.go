@@ -0,0 +1,259 @@
+package tournament
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ekotlikoff/gopoker/internal/model/payouts"
+	model "github.com/ekotlikoff/gopoker/internal/model/table"
+)
+
+type (
+	// SitAndGoConfig define the structure of a SitAndGo
+	SitAndGoConfig struct {
+		// Seats the number of entrants, play starts as soon as they are filled
+		Seats int
+		// BuyIn each entrant pays into the prize pool
+		BuyIn int
+		// StartingStack of chips each entrant starts with
+		StartingStack int
+		// Blinds schedule, it must not be empty
+		Blinds []model.BlindLevel
+		// Payouts the percentage of the prize pool paid to each finishing
+		// place, e.g. {65, 35} pays first and second
+		Payouts []int
+		// Bounty the part of BuyIn put on each entrant's head, paid to the
+		// player that eliminates them rather than into the prize pool
+		Bounty int
+		// Progressive knockout, only half of a bounty is paid and the other
+		// half is added to the eliminator's own bounty
+		Progressive bool
+		// TimeToBet each player has to act on their turn
+		TimeToBet time.Duration
+		// TimeBetweenHands to pause after each hand
+		TimeBetweenHands time.Duration
+	}
+
+	// SitAndGo is a single table tournament, registration is open until the
+	// seats are filled and then play starts automatically
+	SitAndGo struct {
+		Config SitAndGoConfig
+		Table  *model.Table
+		// Bank buy ins are withdrawn from and payouts deposited to, if nil
+		// no chips change hands outside the tournament
+		Bank     model.Bank
+		state    State
+		done     chan struct{}
+		sngMutex sync.Mutex
+		standings
+	}
+)
+
+// NewSitAndGo create a sit and go that is open for registration
+func NewSitAndGo(config SitAndGoConfig) (*SitAndGo, error) {
+	if config.Seats < model.MinPlayersToPlay || config.Seats > model.MaxTableSize {
+		return nil, fmt.Errorf("newsitandgo: seats must be between %d and %d",
+			model.MinPlayersToPlay, model.MaxTableSize)
+	} else if config.StartingStack <= 0 || config.BuyIn < 0 {
+		return nil, errors.New("newsitandgo: invalid buy in or starting stack")
+	} else if config.Bounty < 0 || config.Bounty > config.BuyIn {
+		return nil, errors.New("newsitandgo: bounty must be part of the buy in")
+	} else if err := validPayouts(config.Payouts, config.Seats); err != nil {
+		return nil, fmt.Errorf("newsitandgo: %w", err)
+	}
+	tableConfig, err := model.NewTableConfig(0, config.TimeToBet,
+		config.TimeBetweenHands).WithShortStackBlinds().
+		WithBlindSchedule(config.Blinds)
+	if err != nil {
+		return nil, fmt.Errorf("newsitandgo: %w", err)
+	}
+	sng := &SitAndGo{
+		Config: config, Table: model.NewTableWithConfig(tableConfig),
+		state: Registering, done: make(chan struct{}),
+	}
+	sng.Table.AddHandListener(sng.recordEliminations)
+	return sng, nil
+}
+
+// State of the sit and go
+func (sng *SitAndGo) State() State {
+	sng.sngMutex.Lock()
+	defer sng.sngMutex.Unlock()
+	return sng.state
+}
+
+// Done is closed once the sit and go is finished
+func (sng *SitAndGo) Done() <-chan struct{} {
+	return sng.done
+}
+
+// PrizePool the sum of the entrants' buy ins, less their bounties
+func (sng *SitAndGo) PrizePool() int {
+	sng.sngMutex.Lock()
+	defer sng.sngMutex.Unlock()
+	return sng.prizePool()
+}
+
+func (sng *SitAndGo) prizePool() int {
+	return (sng.Config.BuyIn - sng.Config.Bounty) * len(sng.registered)
+}
+
+// Bounty on the player's head
+func (sng *SitAndGo) Bounty(player *model.Player) int {
+	sng.sngMutex.Lock()
+	defer sng.sngMutex.Unlock()
+	return sng.bounty(player)
+}
+
+// Register the player, seating them with the starting stack. Play starts once
+// the last seat is filled.
+func (sng *SitAndGo) Register(player *model.Player) error {
+	sng.sngMutex.Lock()
+	defer sng.sngMutex.Unlock()
+	if sng.state != Registering {
+		return errors.New("register: registration is closed")
+	}
+	if sng.isRegistered(player.Name) {
+		return errors.New("register: player is already registered")
+	}
+	if sng.Bank != nil {
+		if err := sng.Bank.Withdraw(player.Name, sng.Config.BuyIn); err != nil {
+			return fmt.Errorf("register: %w", err)
+		}
+	}
+	player.Funds = sng.Config.StartingStack
+	if err := sng.Table.SitDown(player, sng.emptySeat()); err != nil {
+		refund(sng.Bank, player, sng.Config.BuyIn)
+		return fmt.Errorf("register: %w", err)
+	}
+	sng.registered = append(sng.registered, player)
+	sng.addBounty(player, sng.Config.Bounty)
+	log.Println(player.Name, "registered,", len(sng.registered), "of",
+		sng.Config.Seats)
+	if len(sng.registered) == sng.Config.Seats {
+		sng.state = Running
+		go sng.play()
+	}
+	return nil
+}
+
+// Unregister the player and refund their buy in, only while registering
+func (sng *SitAndGo) Unregister(player *model.Player) error {
+	sng.sngMutex.Lock()
+	defer sng.sngMutex.Unlock()
+	if sng.state != Registering {
+		return errors.New("unregister: the tournament has started")
+	}
+	if !sng.unregister(player) {
+		return errors.New("unregister: player is not registered")
+	}
+	sng.Table.Unseat(player)
+	delete(sng.bounties, player)
+	player.Funds = 0
+	refund(sng.Bank, player, sng.Config.BuyIn)
+	return nil
+}
+
+func (sng *SitAndGo) emptySeat() int {
+	for i, p := range sng.Table.Players {
+		if p == nil {
+			return i
+		}
+	}
+	return len(sng.Table.Players)
+}
+
+func (sng *SitAndGo) play() {
+	log.Println("Sit and go is starting")
+	err := sng.Table.Play()
+	log.Println("Sit and go table stopped,", err)
+	sng.sngMutex.Lock()
+	defer sng.sngMutex.Unlock()
+	if sng.state != Finished {
+		log.Println("ERROR sit and go stopped before it was finished")
+	}
+}
+
+// recordEliminations is a model.HandListener giving players that lost their
+// last chip in the hand a finishing place, and paying out once one remains
+func (sng *SitAndGo) recordEliminations(table *model.Table,
+	result *model.HandResult) {
+	sng.sngMutex.Lock()
+	defer sng.sngMutex.Unlock()
+	if sng.state == Finished {
+		return
+	}
+	sng.cancelDeal()
+	sng.collectBounties(sng.Bank, result.Eliminations, sng.Config.Progressive)
+	eliminated := []*model.Player{}
+	for player := range result.Net {
+		if player.Funds == 0 {
+			eliminated = append(eliminated, player)
+			player.StandUp()
+		}
+	}
+	sng.eliminate(eliminated, startingStacks(result))
+	remaining := sng.remaining()
+	if len(remaining) == 1 {
+		sng.finish(remaining[0])
+		remaining[0].StandUp()
+		sng.collectOwnBounty(sng.Bank, remaining[0])
+		sng.pay(sng.Bank, sng.prizePool(), sng.Config.Payouts)
+		sng.state = Finished
+		close(sng.done)
+	}
+}
+
+// ProposeDeal to the remaining players dividing the prizes left between them
+// by their stacks, only between hands. It is cancelled if a hand finishes
+// before every player accepts.
+func (sng *SitAndGo) ProposeDeal(kind payouts.DealKind) (*payouts.Deal, error) {
+	sng.sngMutex.Lock()
+	defer sng.sngMutex.Unlock()
+	if sng.state != Running {
+		return nil, errors.New("proposedeal: the sit and go is not running")
+	}
+	stacks, ok := sng.Table.Stacks()
+	if !ok {
+		return nil, fmt.Errorf("proposedeal: %w", errMidHand)
+	}
+	deal, err := sng.proposeDeal(kind, sng.prizePool(), sng.Config.Payouts,
+		stacks)
+	if err != nil {
+		return nil, fmt.Errorf("proposedeal: %w", err)
+	}
+	return deal, nil
+}
+
+// AcceptDeal on behalf of the player, only between hands so nobody accepts
+// knowing their cards. The sit and go is finished once every remaining player
+// has accepted.
+func (sng *SitAndGo) AcceptDeal(player *model.Player) error {
+	sng.sngMutex.Lock()
+	defer sng.sngMutex.Unlock()
+	if sng.state != Running {
+		return errors.New("acceptdeal: the sit and go is not running")
+	} else if _, ok := sng.Table.Stacks(); !ok {
+		return fmt.Errorf("acceptdeal: %w", errMidHand)
+	}
+	agreed, err := sng.acceptDeal(sng.Bank, player, sng.prizePool(),
+		sng.Config.Payouts)
+	if err != nil {
+		return fmt.Errorf("acceptdeal: %w", err)
+	}
+	if agreed {
+		sng.state = Finished
+		close(sng.done)
+	}
+	return nil
+}
+
+// Results the finishing places decided so far, best place first
+func (sng *SitAndGo) Results() []Finish {
+	sng.sngMutex.Lock()
+	defer sng.sngMutex.Unlock()
+	return sng.results()
+}
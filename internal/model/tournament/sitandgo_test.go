@@ -0,0 +1,274 @@
+package tournament
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ekotlikoff/gopoker/internal/model/payouts"
+	model "github.com/ekotlikoff/gopoker/internal/model/table"
+)
+
+func TestSitAndGo(t *testing.T) {
+	levels := []model.BlindLevel{}
+	for bb := 100; bb <= 3200; bb *= 2 {
+		levels = append(levels,
+			model.BlindLevel{SmallBlind: bb / 2, BigBlind: bb, Hands: 1})
+	}
+	sng, err := NewSitAndGo(SitAndGoConfig{
+		Seats: 3, BuyIn: 100, StartingStack: 1000, Blinds: levels,
+		Payouts: []int{70, 30}, TimeToBet: time.Millisecond * 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bank := model.NewMemoryBank()
+	sng.Bank = bank
+	players := []*model.Player{
+		model.NewPlayer("Leto"), model.NewPlayer("Paul"), model.NewPlayer("Jessica"),
+	}
+	for _, p := range players {
+		bank.Deposit(p.Name, 100)
+	}
+	if err := sng.Register(players[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := sng.Register(players[0]); err == nil {
+		t.Error("expected registering twice to fail")
+	}
+	if err := sng.Register(players[1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := sng.Unregister(players[1]); err != nil {
+		t.Fatal(err)
+	} else if bank.Balance("Paul") != 100 {
+		t.Error("expected Paul's buy in to be refunded got", bank.Balance("Paul"))
+	}
+	for _, p := range players[1:] {
+		if err := sng.Register(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if sng.State() != Running {
+		t.Error("expected the sit and go to start once the seats are filled")
+	}
+	select {
+	case <-sng.Done():
+	case <-time.After(time.Second * 5):
+		t.Fatal("sit and go did not finish")
+	}
+	results := sng.Results()
+	if len(results) != 3 {
+		t.Fatal("expected 3 finishes got", results)
+	}
+	total := 0
+	for i, finish := range results {
+		if finish.Place != i+1 {
+			t.Error("expected place", i+1, "got", finish.Place)
+		}
+		total += bank.Balance(finish.Player.Name)
+	}
+	if results[0].Payout != 210 || results[1].Payout != 90 ||
+		results[2].Payout != 0 {
+		t.Error("expected payouts of 210, 90 and 0 got", results)
+	}
+	if total != 300 {
+		t.Error("expected the prize pool of 300 to be paid out got", total)
+	}
+}
+
+func TestSitAndGoDeal(t *testing.T) {
+	sng, err := NewSitAndGo(SitAndGoConfig{
+		Seats: 3, BuyIn: 100, StartingStack: 1000,
+		Blinds:  []model.BlindLevel{{SmallBlind: 50, BigBlind: 100, Hands: 1}},
+		Payouts: []int{50, 30, 20}, TimeToBet: time.Millisecond * 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bank := model.NewMemoryBank()
+	sng.Bank = bank
+	players := []*model.Player{
+		model.NewPlayer("Leto"), model.NewPlayer("Paul"), model.NewPlayer("Jessica"),
+	}
+	if _, err := sng.ProposeDeal(payouts.ICMDeal); err == nil {
+		t.Error("expected a deal before the sit and go starts to fail")
+	}
+	held := make(chan struct{})
+	sng.Table.AddHandListener(func(table *model.Table,
+		result *model.HandResult) {
+		held <- struct{}{}
+		<-held
+	})
+	for _, p := range players {
+		bank.Deposit(p.Name, 100)
+		if err := sng.Register(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-held
+	deal, err := sng.ProposeDeal(payouts.ICMDeal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := 0
+	for _, p := range players {
+		total += deal.Amount(p.Name)
+		if err := sng.AcceptDeal(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case <-sng.Done():
+	default:
+		t.Fatal("expected the sit and go to finish once the deal was agreed")
+	}
+	if total != 300 {
+		t.Error("expected the prize pool of 300 to be dealt got", deal.Amounts)
+	}
+	for _, p := range players {
+		if bank.Balance(p.Name) != deal.Amount(p.Name) {
+			t.Error("expected", p.Name, "to be paid", deal.Amount(p.Name), "got",
+				bank.Balance(p.Name))
+		}
+	}
+	if len(sng.Results()) != 3 {
+		t.Error("expected every player to be placed got", sng.Results())
+	}
+}
+
+func TestSitAndGoDealAcceptedMidHand(t *testing.T) {
+	sng, err := NewSitAndGo(SitAndGoConfig{
+		Seats: 3, BuyIn: 100, StartingStack: 1000,
+		Blinds:  []model.BlindLevel{{SmallBlind: 50, BigBlind: 100, Hands: 1}},
+		Payouts: []int{50, 30, 20}, TimeToBet: time.Millisecond * 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	players := []*model.Player{
+		model.NewPlayer("Leto"), model.NewPlayer("Paul"), model.NewPlayer("Jessica"),
+	}
+	held := make(chan struct{})
+	sng.Table.AddHandListener(func(table *model.Table,
+		result *model.HandResult) {
+		held <- struct{}{}
+		<-held
+	})
+	for _, p := range players {
+		if err := sng.Register(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-held
+	if _, err := sng.ProposeDeal(payouts.ChipChop); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range players[:2] {
+		if err := sng.AcceptDeal(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	timeToBet := time.Minute
+	if _, err := sng.Table.ModifyConfig(model.ConfigChange{
+		TimeToBet: &timeToBet}); err != nil {
+		t.Fatal(err)
+	}
+	held <- struct{}{}
+	for retries := 0; sng.Table.State(nil).BetTurnSeat < 0; retries++ {
+		if retries == 1000 {
+			t.Fatal("expected the next hand to be dealt")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := sng.AcceptDeal(players[2]); !errors.Is(err, errMidHand) {
+		t.Error("expected accepting a deal mid hand to fail got", err)
+	}
+	select {
+	case <-sng.Done():
+		t.Error("expected the sit and go to keep running")
+	default:
+	}
+}
+
+func TestDealPaysPlacesAlreadyDecided(t *testing.T) {
+	s := standings{bounties: map[*model.Player]int{},
+		bountiesWon: map[*model.Player]int{}}
+	for _, name := range []string{"A", "B", "C", "D"} {
+		s.registered = append(s.registered, model.NewPlayer(name))
+	}
+	s.finish(s.registered[3])
+	stacks := map[*model.Player]int{}
+	for _, p := range s.registered[:3] {
+		stacks[p] = 1000
+	}
+	percentages := []int{40, 30, 20, 10}
+	if _, err := s.proposeDeal(payouts.ChipChop, 1000, percentages,
+		stacks); err != nil {
+		t.Fatal(err)
+	}
+	bank := model.NewMemoryBank()
+	for _, p := range s.registered[:3] {
+		if _, err := s.acceptDeal(bank, p, 1000, percentages); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if bank.Balance("D") != 100 {
+		t.Error("expected the busted fourth place to be paid 100 got",
+			bank.Balance("D"))
+	}
+	total := 0
+	for _, p := range s.registered {
+		total += bank.Balance(p.Name)
+	}
+	if total != 1000 {
+		t.Error("expected the prize pool of 1000 to be paid out got", total)
+	}
+}
+
+func TestSitAndGoProgressiveKnockout(t *testing.T) {
+	levels := []model.BlindLevel{}
+	for bb := 100; bb <= 3200; bb *= 2 {
+		levels = append(levels,
+			model.BlindLevel{SmallBlind: bb / 2, BigBlind: bb, Hands: 1})
+	}
+	sng, err := NewSitAndGo(SitAndGoConfig{
+		Seats: 3, BuyIn: 100, StartingStack: 1000, Blinds: levels,
+		Payouts: []int{100}, Bounty: 40, Progressive: true,
+		TimeToBet: time.Millisecond * 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bank := model.NewMemoryBank()
+	sng.Bank = bank
+	players := []*model.Player{
+		model.NewPlayer("Leto"), model.NewPlayer("Paul"), model.NewPlayer("Jessica"),
+	}
+	for _, p := range players {
+		bank.Deposit(p.Name, 100)
+		if err := sng.Register(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if sng.Bounty(players[0]) != 40 || sng.PrizePool() != 180 {
+		t.Error("expected a bounty of 40 and a prize pool of 180 got",
+			sng.Bounty(players[0]), sng.PrizePool())
+	}
+	select {
+	case <-sng.Done():
+	case <-time.After(time.Second * 5):
+		t.Fatal("sit and go did not finish")
+	}
+	total, bounties := 0, 0
+	for _, finish := range sng.Results() {
+		total += bank.Balance(finish.Player.Name)
+		bounties += finish.Bounties
+	}
+	if bounties != 120 {
+		t.Error("expected every bounty to be won got", bounties)
+	}
+	if total != 300 {
+		t.Error("expected the buy ins of 300 to be paid out got", total)
+	}
+}
@@ -0,0 +1,262 @@
+// Package tournament runs poker tournaments on top of model Tables, where
+// players buy in for a fixed stack and play until one player has every chip.
+package tournament
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/ekotlikoff/gopoker/internal/model/payouts"
+	model "github.com/ekotlikoff/gopoker/internal/model/table"
+)
+
+type (
+	// State of a tournament
+	State int
+
+	// Finish is a player's finishing place in a tournament and what they won
+	Finish struct {
+		Player *model.Player
+		Place  int
+		Payout int
+		// Bounties the player won by eliminating other players
+		Bounties int
+	}
+
+	// standings of the players registered for a tournament, places are
+	// decided from last to first as players are eliminated
+	standings struct {
+		registered []*model.Player
+		finishes   []Finish
+		// busted players waiting to re-enter, they are only placed once
+		// registration closes
+		busted []*model.Player
+		// bounties on each player's head and those won by each player
+		bounties    map[*model.Player]int
+		bountiesWon map[*model.Player]int
+		// deal proposed to the remaining players, cancelled if a hand
+		// finishes before every player accepts it
+		deal *payouts.Deal
+	}
+)
+
+const (
+	// Registering players can register and unregister
+	Registering = State(iota)
+	// Running the tournament is being played
+	Running = State(iota)
+	// Finished every place has been decided and paid
+	Finished = State(iota)
+)
+
+func validPayouts(payouts []int, entrants int) error {
+	total := 0
+	for _, payout := range payouts {
+		if payout < 0 {
+			return errors.New("payouts cannot be negative")
+		}
+		total += payout
+	}
+	if len(payouts) == 0 || len(payouts) > entrants || total != 100 {
+		return errors.New("payouts must pay at most every entrant and sum to 100")
+	}
+	return nil
+}
+
+func (s *standings) isRegistered(name string) bool {
+	for _, p := range s.registered {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *standings) unregister(player *model.Player) bool {
+	for i, p := range s.registered {
+		if p == player {
+			s.registered = append(s.registered[:i], s.registered[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// eliminate give each player a finishing place, players eliminated in the
+// same hand are placed by the stack they started the hand with
+func (s *standings) eliminate(eliminated []*model.Player,
+	startingStacks map[*model.Player]int) {
+	sort.Slice(eliminated, func(i, j int) bool {
+		// The smallest starting stack finishes lowest
+		return startingStacks[eliminated[i]] < startingStacks[eliminated[j]]
+	})
+	for _, player := range eliminated {
+		s.finish(player)
+	}
+}
+
+func (s *standings) finish(player *model.Player) {
+	place := len(s.registered) - len(s.finishes)
+	log.Println(player.Name, "finished in place", place)
+	s.finishes = append(s.finishes, Finish{Player: player, Place: place})
+}
+
+// placeBusted give the busted players a finishing place, those that busted
+// first finish lowest
+func (s *standings) placeBusted() {
+	for _, player := range s.busted {
+		s.finish(player)
+	}
+	s.busted = nil
+}
+
+// remaining the registered players that have not yet finished
+func (s *standings) remaining() []*model.Player {
+	remaining := []*model.Player{}
+	for _, p := range s.registered {
+		finished := false
+		for _, f := range s.finishes {
+			finished = finished || f.Player == p
+		}
+		for _, b := range s.busted {
+			finished = finished || b == p
+		}
+		if !finished {
+			remaining = append(remaining, p)
+		}
+	}
+	return remaining
+}
+
+// pay award the prize pool by finishing place and deposit each payout to bank,
+// if not nil. The remainder of any rounding goes to the winner.
+func (s *standings) pay(bank model.Bank, prizePool int, percentages []int) {
+	s.payPlaces(prizePool, percentages)
+	s.deposit(bank)
+}
+
+// payPlaces set the payout of each place decided so far
+func (s *standings) payPlaces(prizePool int, percentages []int) {
+	amounts := payouts.Amounts(prizePool, percentages)
+	for i := range s.finishes {
+		finish := &s.finishes[i]
+		if finish.Place <= len(amounts) {
+			finish.Payout = amounts[finish.Place-1]
+		}
+	}
+}
+
+func (s *standings) deposit(bank model.Bank) {
+	for _, finish := range s.finishes {
+		if finish.Payout > 0 && bank != nil {
+			if err := bank.Deposit(finish.Player.Name, finish.Payout); err != nil {
+				log.Println("ERROR failed to pay", finish.Player.Name, err)
+			}
+		}
+	}
+}
+
+// proposeDeal to the remaining players dividing the prizes of the places left
+// by their stacks between hands
+func (s *standings) proposeDeal(kind payouts.DealKind, prizePool int,
+	percentages []int, tableStacks map[*model.Player]int) (*payouts.Deal,
+	error) {
+	remaining := s.remaining()
+	if len(remaining) < 2 {
+		return nil, errors.New("there must be at least two players left")
+	}
+	prizes := payouts.Amounts(prizePool, percentages)
+	if len(prizes) > len(remaining) {
+		prizes = prizes[:len(remaining)]
+	}
+	names, stacks := []string{}, []int{}
+	for _, player := range remaining {
+		stack, ok := tableStacks[player]
+		if !ok {
+			return nil, fmt.Errorf("%s is not seated", player.Name)
+		}
+		names = append(names, player.Name)
+		stacks = append(stacks, stack)
+	}
+	deal, err := payouts.NewDeal(kind, names, stacks, prizes)
+	if err != nil {
+		return nil, err
+	}
+	log.Println("Proposed a", kind, "deal of", deal.Amounts, "to", names)
+	s.deal = deal
+	return deal, nil
+}
+
+// errMidHand refuses proposing or accepting a deal while a hand is being
+// played, when some of the players' chips are in its pots and they have seen
+// their cards
+var errMidHand = errors.New("deals can only be made between hands")
+
+// acceptDeal on behalf of the player, once every player has accepted the
+// places already decided are paid their prizes, the remaining places are
+// decided by, and paid, what the deal agreed and each player collects the
+// bounty on their own head. Returns whether the deal was agreed.
+func (s *standings) acceptDeal(bank model.Bank, player *model.Player,
+	prizePool int, percentages []int) (bool, error) {
+	if s.deal == nil {
+		return false, errors.New("there is no deal to accept")
+	}
+	agreed, err := s.deal.Accept(player.Name)
+	if err != nil || !agreed {
+		return false, err
+	}
+	log.Println("Every player accepted the deal")
+	s.payPlaces(prizePool, percentages)
+	remaining := s.remaining()
+	sort.Slice(remaining, func(i, j int) bool {
+		return s.deal.Amount(remaining[i].Name) < s.deal.Amount(remaining[j].Name)
+	})
+	for _, p := range remaining {
+		s.finish(p)
+		s.finishes[len(s.finishes)-1].Payout = s.deal.Amount(p.Name)
+		s.collectOwnBounty(bank, p)
+		p.StandUp()
+	}
+	s.deposit(bank)
+	return true, nil
+}
+
+// cancelDeal that has not been agreed, the stacks it was proposed for have
+// changed
+func (s *standings) cancelDeal() {
+	if s.deal != nil && !s.deal.Accepted() {
+		log.Println("Cancelled the deal, a hand finished before it was agreed")
+	}
+	s.deal = nil
+}
+
+// results the finishing places decided so far, best place first
+func (s *standings) results() []Finish {
+	results := make([]Finish, len(s.finishes))
+	for i, f := range s.finishes {
+		f.Bounties = s.bountiesWon[f.Player]
+		results[len(results)-1-i] = f
+	}
+	return results
+}
+
+// refund the buy in to the player, if bank is not nil
+func refund(bank model.Bank, player *model.Player, buyIn int) {
+	if bank == nil {
+		return
+	}
+	if err := bank.Deposit(player.Name, buyIn); err != nil {
+		log.Println("ERROR failed to refund buy in", err)
+	}
+}
+
+// startingStacks of the players in a hand, derived from their net result
+func startingStacks(result *model.HandResult) map[*model.Player]int {
+	stacks := make(map[*model.Player]int)
+	for player, net := range result.Net {
+		stacks[player] = player.Funds - net
+	}
+	return stacks
+}
@@ -0,0 +1,221 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ekotlikoff/gopoker/internal/model/group"
+)
+
+const (
+	// chatHistorySize messages kept to send to connecting clients
+	chatHistorySize = 50
+	// maxChatLength of a player's message in characters
+	maxChatLength = 200
+	// chatBurst messages a player can send within chatWindow
+	chatBurst  = 5
+	chatWindow = 10 * time.Second
+	// kickBan how long a kicked player is refused from the table
+	kickBan = 15 * time.Minute
+)
+
+type (
+	// ChatFilter checks a player's chat message before it is sent, returning
+	// the message to send, e.g. with profanity masked, or an error to refuse
+	// it
+	ChatFilter func(playerName string, message string) (string, error)
+
+	// ChatMessage a message in a table's chat, from a player or the dealer
+	ChatMessage struct {
+		PlayerName string `json:",omitempty"`
+		// Dealer if the message was sent by the server, e.g. a hand's result
+		Dealer  bool `json:",omitempty"`
+		Message string
+		Time    time.Time
+	}
+
+	// tableChat the chat of a table, the players muted or kicked from it
+	// by admins and when each player recently sent a message. Players are
+	// kept by name, the only identity their sessions have, so a mute or ban
+	// does not follow a player who logs in under another name.
+	tableChat struct {
+		history []ChatMessage
+		filter  ChatFilter
+		muted   map[string]bool
+		kicked  map[string]time.Time
+		sent    map[string][]time.Time
+	}
+)
+
+func newTableChat(filter ChatFilter) tableChat {
+	return tableChat{
+		filter: filter, muted: make(map[string]bool),
+		kicked: make(map[string]time.Time), sent: make(map[string][]time.Time),
+	}
+}
+
+// SetChatFilter check every player's chat message with filter, at every
+// table, nil to stop filtering
+func (ts *TableServer) SetChatFilter(filter ChatFilter) {
+	ts.tablesMutex.Lock()
+	defer ts.tablesMutex.Unlock()
+	ts.chatFilter = filter
+	for _, hub := range ts.tables {
+		hub.hubMutex.Lock()
+		hub.chat.filter = filter
+		hub.hubMutex.Unlock()
+	}
+}
+
+// sendChat send the client's message to the table's chat, unless they are
+// muted, sending too quickly or it is refused by the chat filter
+func (hub *tableHub) sendChat(client *tableClient, message string) error {
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return errors.New("chat: empty message")
+	} else if utf8.RuneCountInString(message) > maxChatLength {
+		return fmt.Errorf("chat: message is longer than %d characters",
+			maxChatLength)
+	}
+	name := client.player.Name
+	hub.hubMutex.Lock()
+	if hub.chat.muted[name] {
+		hub.hubMutex.Unlock()
+		return errors.New("chat: you are muted")
+	}
+	now := time.Now()
+	var recent []time.Time
+	for _, sent := range hub.chat.sent[name] {
+		if now.Sub(sent) < chatWindow {
+			recent = append(recent, sent)
+		}
+	}
+	hub.chat.sent[name] = recent
+	filter := hub.chat.filter
+	hub.hubMutex.Unlock()
+	if len(recent) >= chatBurst {
+		return errors.New("chat: you are sending messages too quickly")
+	} else if filter != nil {
+		var err error
+		if message, err = filter(name, message); err != nil {
+			return fmt.Errorf("chat: %w", err)
+		}
+	}
+	hub.hubMutex.Lock()
+	defer hub.hubMutex.Unlock()
+	hub.chat.sent[name] = append(hub.chat.sent[name], now)
+	hub.postChat(ChatMessage{PlayerName: name, Message: message, Time: now})
+	return nil
+}
+
+// dealerSays send a message from the dealer to the table's chat. Called
+// holding hubMutex.
+func (hub *tableHub) dealerSays(format string, args ...interface{}) {
+	hub.postChat(ChatMessage{
+		Dealer: true, Message: fmt.Sprintf(format, args...), Time: time.Now(),
+	})
+}
+
+// postChat keep the message in the chat's history and send it to every
+// client. Called holding hubMutex.
+func (hub *tableHub) postChat(message ChatMessage) {
+	hub.chat.history = append(hub.chat.history, message)
+	if len(hub.chat.history) > chatHistorySize {
+		hub.chat.history = hub.chat.history[len(hub.chat.history)-
+			chatHistorySize:]
+	}
+	response := newResponse(ChatResponseT)
+	response.Chat = []ChatMessage{message}
+	for client := range hub.clients {
+		hub.send(client, response)
+	}
+}
+
+// chatHistory a copy of the chat's recent messages. Called holding hubMutex.
+func (hub *tableHub) chatHistory() []ChatMessage {
+	return append([]ChatMessage(nil), hub.chat.history...)
+}
+
+// mute or unmute the player named name in the table's chat, see moderate
+func (hub *tableHub) mute(by *tableClient, name string, muted bool) error {
+	if err := moderate(by, name); err != nil {
+		return fmt.Errorf("mute: %w", err)
+	}
+	hub.hubMutex.Lock()
+	defer hub.hubMutex.Unlock()
+	if hub.chat.muted[name] == muted {
+		return fmt.Errorf("mute: %s is already %s", name, mutedString(muted))
+	}
+	hub.chat.muted[name] = muted
+	hub.dealerSays("%s was %s by %s", name, mutedString(muted), by.player.Name)
+	return nil
+}
+
+// moderate if the client may mute or kick the player named name. Like
+// managing the group's members, admins can moderate players and only the
+// owner can moderate admins.
+func moderate(by *tableClient, name string) error {
+	if !by.admin {
+		return errors.New("only admins can moderate players")
+	} else if name == by.player.Name {
+		return errors.New("cannot moderate yourself")
+	}
+	byRole, _ := by.group.Role(by.player.Name)
+	role, _ := by.group.Role(name)
+	if role.AtLeast(group.Admin) && byRole != group.Owner {
+		return errors.New("only the owner can moderate admins")
+	}
+	return nil
+}
+
+func mutedString(muted bool) string {
+	if muted {
+		return "muted"
+	}
+	return "unmuted"
+}
+
+// kick the player named name from the table, see moderate. The player is
+// disconnected, stood up once they are not playing a hand and refused from
+// the table for kickBan, under their name only, see tableChat.
+func (hub *tableHub) kick(by *tableClient, name string) error {
+	if err := moderate(by, name); err != nil {
+		return fmt.Errorf("kick: %w", err)
+	}
+	player := hub.table.Player(name)
+	hub.hubMutex.Lock()
+	for client := range hub.clients {
+		if client.player.Name == name {
+			player = client.player
+			hub.send(client, errorResponse(errors.New(
+				"you were kicked from the table")))
+			hub.drop(client)
+		}
+	}
+	if player == nil {
+		hub.hubMutex.Unlock()
+		return fmt.Errorf("kick: %s is not at the table", name)
+	}
+	hub.chat.kicked[name] = time.Now().Add(kickBan)
+	hub.dealerSays("%s was kicked from the table by %s", name, by.player.Name)
+	hub.hubMutex.Unlock()
+	hub.table.StandUp(player)
+	hub.table.StopWatching(player)
+	hub.table.SetConnected(player, false)
+	hub.stateChanged(hub.table, nil)
+	return nil
+}
+
+// banned if the player was kicked from the table recently. Called holding
+// hubMutex.
+func (hub *tableHub) banned(name string) bool {
+	until, ok := hub.chat.kicked[name]
+	if ok && time.Now().After(until) {
+		delete(hub.chat.kicked, name)
+		return false
+	}
+	return ok
+}
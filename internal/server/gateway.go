@@ -1,16 +1,22 @@
 package gateway
 
 import (
+	"bufio"
+	"context"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
 	"time"
 
 	model "github.com/ekotlikoff/gopoker/internal/model/table"
@@ -24,6 +30,9 @@ const (
 	acceptableRequestPeriodMS   = 100
 	maxBurstOfRequests          = 10
 	maxTimeToWaitForRateLimiter = 2 * time.Second
+	// shutdownTimeout how long the hands being played have to finish when
+	// shutting down
+	shutdownTimeout = 5 * time.Minute
 )
 
 var (
@@ -99,6 +108,9 @@ type (
 		WSBackend *url.URL
 		BasePath  string
 		Port      int
+		// Groups whose REST API and table websockets are served, if nil a
+		// server with no groups is created
+		Groups *GroupServer
 	}
 
 	// Credentials for authentication
@@ -111,11 +123,8 @@ type (
 func (gw *Gateway) Serve() {
 	cleanupChan := make(chan struct{})
 	setupRateLimiter(cleanupChan)
-	wsBackendProxy := httputil.NewSingleHostReverseProxy(gw.WSBackend)
-	wsBackendProxy.ModifyResponse = func(res *http.Response) error {
-		gatewayResponseMetric.WithLabelValues(
-			res.Request.URL.Path, res.Request.Method, res.Status).Inc()
-		return nil
+	if gw.Groups == nil {
+		gw.Groups = NewGroupServer()
 	}
 	mux := http.NewServeMux()
 	bp := gw.BasePath
@@ -130,14 +139,47 @@ func (gw *Gateway) Serve() {
 		func(w http.ResponseWriter, r *http.Request) {
 			http.ServeFile(w, r, os.Getenv("HOME")+"/bin/gochessclient.wasm")
 		})))
-	mux.Handle(bp+"/session", middleware(http.HandlerFunc(Session)))
+	mux.Handle(bp+"/session", middleware(http.HandlerFunc(gw.session)))
+	// Groups and their table websockets
+	groups := middleware(http.StripPrefix(bp, gw.Groups))
+	mux.Handle(bp+"/group", groups)
+	mux.Handle(bp+"/group/", groups)
 	// Websocket backend proxying
-	mux.Handle(bp+"/ws", wsBackendProxy)
+	if gw.WSBackend != nil {
+		wsBackendProxy := httputil.NewSingleHostReverseProxy(gw.WSBackend)
+		wsBackendProxy.ModifyResponse = func(res *http.Response) error {
+			gatewayResponseMetric.WithLabelValues(
+				res.Request.URL.Path, res.Request.Method, res.Status).Inc()
+			return nil
+		}
+		mux.Handle(bp+"/ws", wsBackendProxy)
+	}
 	// Prometheus metrics endpoint
 	mux.Handle(bp+"/metrics", middleware(
 		promhttp.Handler()))
 	log.Println("Gateway server listening on port", gw.Port, "...")
-	http.ListenAndServe(":"+strconv.Itoa(gw.Port), mux)
+	server := &http.Server{Addr: ":" + strconv.Itoa(gw.Port), Handler: mux}
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	select {
+	case err := <-serverErr:
+		log.Println("Gateway server stopped,", err)
+	case <-signals:
+		log.Println("Shutting down, finishing the hands being played")
+		ctx, cancel := context.WithTimeout(context.Background(),
+			shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			log.Println("ERROR failed to shut down the gateway server", err)
+		}
+		if err := gw.Groups.Tables.Stop(ctx); err != nil {
+			log.Println("ERROR tables did not stop in time", err)
+		}
+	}
 	close(cleanupChan)
 }
 
@@ -170,31 +212,36 @@ func (gw *Gateway) handleWebRoot(w http.ResponseWriter, r *http.Request) {
 
 // Session credit to https://www.sohamkamani.com/blog/2018/03/25/golang-session-authentication/
 func Session(w http.ResponseWriter, r *http.Request) {
+	(&Gateway{}).session(w, r)
+}
+
+// session handles the session like Session, including the table the player is
+// seated at in the gateway's groups
+func (gw *Gateway) session(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodGet {
-		getSession(w, r)
+		gw.getSession(w, r)
 	} else if r.Method == http.MethodPost {
 		newSession(w, r)
 	}
 }
 
-func getSession(w http.ResponseWriter, r *http.Request) {
+func (gw *Gateway) getSession(w http.ResponseWriter, r *http.Request) {
 	tracer := opentracing.GlobalTracer()
 	SessionSpan := tracer.StartSpan("GETSession")
 	defer SessionSpan.Finish()
 	player := GetSession(w, r)
-	currentMatchResponse := SessionResponse{}
 	if player == nil {
 		return
-	} else if player.GetTable() == nil {
-		log.Println("Found session,", player.Name)
-		currentMatchResponse = SessionResponse{
-			Credentials: Credentials{Username: player.Name},
-		}
+	}
+	log.Println("Found session,", player.Name)
+	currentMatchResponse := SessionResponse{
+		Credentials: Credentials{Username: player.Name},
+	}
+	if gw.Groups != nil {
+		currentMatchResponse.Match, currentMatchResponse.InMatch =
+			gw.Groups.Tables.currentMatch(player)
 	} else {
-		currentMatchResponse = SessionResponse{
-			Credentials: Credentials{Username: player.Name},
-			InMatch:     true,
-		}
+		currentMatchResponse.InMatch = player.GetTable() != nil
 	}
 	if err := json.NewEncoder(w).Encode(currentMatchResponse); err != nil {
 		log.Println(err)
@@ -286,8 +333,14 @@ func GetSession(w http.ResponseWriter, r *http.Request) *model.Player {
 	return player
 }
 
-// CurrentMatch serializable struct to bring client up to speed
+// CurrentMatch serializable struct to bring client up to speed, the table the
+// player is seated at and their view of it. The client reconnects to the
+// table's websocket with ?since=Sequence to receive the events it missed.
 type CurrentMatch struct {
+	GroupID  string
+	TableID  string
+	Sequence int
+	State    *model.TableState `json:",omitempty"`
 }
 
 // SessionResponse serializable struct to send client's session
@@ -308,6 +361,16 @@ func (w *statusWriter) WriteHeader(status int) {
 	w.ResponseWriter.WriteHeader(status)
 }
 
+// Hijack the connection, e.g. to upgrade it to a websocket
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("hijack: response writer is not a hijacker")
+	}
+	w.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
 // rateLimiterMiddleware handles the request by first blocking until the rate
 // limiter says it is acceptable to proceed.
 func rateLimiterMiddleware(handler http.Handler) http.HandlerFunc {
@@ -0,0 +1,295 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ekotlikoff/gopoker/internal/model/group"
+	"github.com/ekotlikoff/gopoker/internal/model/ledger"
+	model "github.com/ekotlikoff/gopoker/internal/model/table"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	defaultTimeToBet        = 30 * time.Second
+	defaultTimeBetweenHands = 5 * time.Second
+)
+
+type (
+	// GroupServer serves the REST API of groups and the websockets of their
+	// tables
+	GroupServer struct {
+		Groups *group.Directory
+		Tables *TableServer
+		// Ledger of the members' bankrolls, the buy ins at the groups' tables
+		// are withdrawn from them
+		Ledger *ledger.Ledger
+	}
+
+	// GroupRequest create a group named Name
+	GroupRequest struct {
+		Name string
+	}
+
+	// ModifyGroupRequest add Member to the group or change their Role, remove
+	// them from it, or add Credit, which may be negative, to their bankroll
+	ModifyGroupRequest struct {
+		Member string
+		Role   group.Role
+		Remove bool
+		Credit int
+	}
+
+	// TableRequest the config of a table to create, or the changes to make
+	// to a table's config. Missing fields are left at their defaults or
+	// unchanged, the buy in limits are only set when a table is created.
+	TableRequest struct {
+		model.ConfigChange
+		MinBuyIn int
+		MaxBuyIn int
+	}
+
+	// TableResponse a table's ID, the config its next hand is played with and
+	// whether it is playing
+	TableResponse struct {
+		ID      string
+		Config  model.ConfigState
+		Playing bool
+	}
+
+	// GroupResponse a group and the requesting player's role in it
+	GroupResponse struct {
+		ID      string
+		Name    string
+		Role    group.Role
+		Members []group.Member `json:",omitempty"`
+		Tables  []string       `json:",omitempty"`
+	}
+)
+
+// NewGroupServer create a server with no groups
+func NewGroupServer() *GroupServer {
+	bankrolls, err := ledger.NewLedger(nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return &GroupServer{
+		Groups: group.NewDirectory(), Tables: NewTableServer(), Ledger: bankrolls,
+	}
+}
+
+// ServeHTTP route /group, /group/{id}, /group/{id}/table and
+// /group/{id}/table/{id} requests, every request needs a session
+func (gs *GroupServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if path[0] != "group" {
+		http.NotFound(w, r)
+		return
+	}
+	player := GetSession(w, r)
+	if player == nil {
+		return
+	}
+	if len(path) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			gs.listGroups(w, player.Name)
+		case http.MethodPost:
+			gs.createGroup(w, r, player.Name)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+		return
+	}
+	g := gs.Groups.Get(path[1])
+	if g == nil {
+		http.NotFound(w, r)
+		return
+	}
+	role, ok := g.Role(player.Name)
+	if !ok {
+		httpError(w, http.StatusForbidden,
+			errors.New("not a member of the group"))
+		return
+	}
+	switch {
+	case len(path) == 2 && r.Method == http.MethodGet:
+		writeResponse(w, groupResponse(g, role, true))
+	case len(path) == 2 && r.Method == http.MethodPost:
+		gs.modifyGroup(w, r, g, player.Name)
+	case len(path) == 3 && path[2] == "table" && r.Method == http.MethodPost:
+		gs.createTable(w, r, g, player.Name)
+	case len(path) == 4 && path[2] == "table" && g.Table(path[3]) != nil:
+		if r.Method == http.MethodPost {
+			gs.modifyTable(w, r, g, path[3], role)
+		} else {
+			gs.Tables.serve(w, r, player, g.ID, path[3], g)
+		}
+	case len(path) == 2 || len(path) == 3 && path[2] == "table":
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (gs *GroupServer) listGroups(w http.ResponseWriter, name string) {
+	groups := []GroupResponse{}
+	for _, g := range gs.Groups.ForMember(name) {
+		role, _ := g.Role(name)
+		groups = append(groups, groupResponse(g, role, false))
+	}
+	writeResponse(w, groups)
+}
+
+func (gs *GroupServer) createGroup(w http.ResponseWriter, r *http.Request,
+	name string) {
+	var request GroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		httpError(w, http.StatusBadRequest, err)
+		return
+	}
+	g, err := gs.Groups.Create(request.Name, name)
+	if err != nil {
+		httpError(w, http.StatusBadRequest, err)
+		return
+	}
+	log.Println(name, "created group", g.ID, g.Name)
+	writeResponse(w, groupResponse(g, group.Owner, true))
+}
+
+func (gs *GroupServer) modifyGroup(w http.ResponseWriter, r *http.Request,
+	g *group.Group, name string) {
+	var request ModifyGroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		httpError(w, http.StatusBadRequest, err)
+		return
+	}
+	var err error
+	if request.Remove {
+		err = g.RemoveMember(name, request.Member)
+	} else if request.Credit != 0 {
+		err = gs.credit(g, name, request.Member, request.Credit)
+	} else {
+		err = g.SetRole(name, request.Member, request.Role)
+	}
+	if errors.Is(err, group.ErrNotPermitted) {
+		httpError(w, http.StatusForbidden, err)
+		return
+	} else if err != nil {
+		httpError(w, http.StatusBadRequest, err)
+		return
+	}
+	role, ok := g.Role(name)
+	if !ok {
+		// The player left the group
+		return
+	}
+	writeResponse(w, groupResponse(g, role, true))
+}
+
+// credit add amount to the bankroll of the member named name, only admins can
+func (gs *GroupServer) credit(g *group.Group, by string, name string,
+	amount int) error {
+	if role, _ := g.Role(by); !role.AtLeast(group.Admin) {
+		return fmt.Errorf("credit: %w, only admins can credit bankrolls",
+			group.ErrNotPermitted)
+	} else if _, ok := g.Role(name); !ok {
+		return fmt.Errorf("credit: %s is not a member", name)
+	} else if gs.Ledger == nil {
+		return errors.New("credit: the server has no ledger")
+	}
+	if err := gs.Ledger.Adjust(g.ID, name, amount, "credit from "+by); err != nil {
+		return fmt.Errorf("credit: %w", err)
+	}
+	return nil
+}
+
+func (gs *GroupServer) createTable(w http.ResponseWriter, r *http.Request,
+	g *group.Group, name string) {
+	var request TableRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		httpError(w, http.StatusBadRequest, err)
+		return
+	}
+	table := model.NewTableWithConfig(model.NewTableConfig(model.DefaultMinBet,
+		defaultTimeToBet, defaultTimeBetweenHands).WithBuyIn(
+		request.MinBuyIn, request.MaxBuyIn))
+	config, err := table.ModifyConfig(request.ConfigChange)
+	if err != nil {
+		httpError(w, http.StatusBadRequest, err)
+		return
+	}
+	id := uuid.Must(uuid.NewV4()).String()
+	if err := g.AddTable(name, id, table); errors.Is(err,
+		group.ErrNotPermitted) {
+		httpError(w, http.StatusForbidden, err)
+		return
+	} else if err != nil {
+		httpError(w, http.StatusBadRequest, err)
+		return
+	}
+	if gs.Ledger != nil {
+		gs.Ledger.Attach(table, g.ID, id)
+	}
+	if err := gs.Tables.AddTable(g.ID, id, table); err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+	log.Println(name, "created table", id, "in group", g.ID)
+	writeResponse(w, TableResponse{ID: id, Config: config})
+}
+
+// modifyTable change a table's config, applied between hands
+func (gs *GroupServer) modifyTable(w http.ResponseWriter, r *http.Request,
+	g *group.Group, id string, role group.Role) {
+	if !role.AtLeast(group.Admin) {
+		httpError(w, http.StatusForbidden,
+			errors.New("only admins can modify tables"))
+		return
+	}
+	var request TableRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		httpError(w, http.StatusBadRequest, err)
+		return
+	}
+	config, err := g.Table(id).ModifyConfig(request.ConfigChange)
+	if err != nil {
+		httpError(w, http.StatusBadRequest, err)
+		return
+	}
+	gs.Tables.refresh(g.ID, id)
+	writeResponse(w, TableResponse{
+		ID: id, Config: config, Playing: gs.Tables.Playing(g.ID, id),
+	})
+}
+
+// groupResponse describe g to a member with role, with its members and tables
+// if detailed
+func groupResponse(g *group.Group, role group.Role,
+	detailed bool) GroupResponse {
+	response := GroupResponse{ID: g.ID, Name: g.Name, Role: role}
+	if detailed {
+		response.Members = g.Members()
+		response.Tables = g.TableIDs()
+	}
+	return response
+}
+
+func writeResponse(w http.ResponseWriter, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func httpError(w http.ResponseWriter, status int, err error) {
+	log.Println("Request failed,", err)
+	w.WriteHeader(status)
+	w.Write([]byte(err.Error()))
+}
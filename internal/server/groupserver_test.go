@@ -0,0 +1,293 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ekotlikoff/gopoker/internal/model/group"
+	model "github.com/ekotlikoff/gopoker/internal/model/table"
+	"github.com/gofrs/uuid"
+)
+
+// newSessionToken add a session for a new player named name
+func newSessionToken(name string) string {
+	token := uuid.Must(uuid.NewV4()).String()
+	sessionCache.Put(token, model.NewPlayer(name))
+	return token
+}
+
+func request(t *testing.T, server *httptest.Server, token string,
+	method string, path string, body interface{}, response interface{}) int {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	req, err := http.NewRequest(method, server.URL+path, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.AddCookie(&http.Cookie{Name: "session_token", Value: token})
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if response != nil && res.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(res.Body).Decode(response); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return res.StatusCode
+}
+
+func TestGroupServer(t *testing.T) {
+	SetQuiet()
+	server := httptest.NewServer(NewGroupServer())
+	defer server.Close()
+	anna, joe := newSessionToken("Anna"), newSessionToken("Joe")
+	if status := request(t, server, "", http.MethodGet, "/group", nil,
+		nil); status != http.StatusUnauthorized {
+		t.Error("expected a request without a session to fail got", status)
+	}
+	var created GroupResponse
+	request(t, server, anna, http.MethodPost, "/group",
+		GroupRequest{Name: "Friday game"}, &created)
+	if created.ID == "" || created.Role != group.Owner {
+		t.Fatal("expected Anna to own a new group got", created)
+	}
+	path := "/group/" + created.ID
+	if status := request(t, server, joe, http.MethodGet, path, nil,
+		nil); status != http.StatusForbidden {
+		t.Error("expected a non-member to be refused got", status)
+	}
+	if status := request(t, server, anna, http.MethodPost, path,
+		ModifyGroupRequest{Member: "Joe", Role: group.Player},
+		nil); status != http.StatusOK {
+		t.Error("expected Anna to add Joe got", status)
+	}
+	if status := request(t, server, joe, http.MethodPost, path,
+		ModifyGroupRequest{Member: "Bob", Role: group.Player},
+		nil); status != http.StatusForbidden {
+		t.Error("expected a player not to add members got", status)
+	}
+	var groups []GroupResponse
+	request(t, server, joe, http.MethodGet, "/group", nil, &groups)
+	if len(groups) != 1 || groups[0].Role != group.Player {
+		t.Error("expected Joe to be a player in one group got", groups)
+	}
+	var info GroupResponse
+	request(t, server, joe, http.MethodGet, path, nil, &info)
+	if len(info.Members) != 2 {
+		t.Error("expected two members got", info.Members)
+	}
+	if status := request(t, server, joe, http.MethodPost, path,
+		ModifyGroupRequest{Member: "Joe", Remove: true},
+		nil); status != http.StatusOK {
+		t.Error("expected Joe to leave got", status)
+	}
+	if status := request(t, server, joe, http.MethodGet,
+		path+"/table/main", nil, nil); status != http.StatusForbidden {
+		t.Error("expected a former member to be refused got", status)
+	}
+}
+
+func TestGroupServerTables(t *testing.T) {
+	SetQuiet()
+	gs := NewGroupServer()
+	server := httptest.NewServer(gs)
+	defer server.Close()
+	anna, joe := newSessionToken("Anna"), newSessionToken("Joe")
+	var created GroupResponse
+	request(t, server, anna, http.MethodPost, "/group",
+		GroupRequest{Name: "Friday game"}, &created)
+	path := "/group/" + created.ID
+	request(t, server, anna, http.MethodPost, path,
+		ModifyGroupRequest{Member: "Joe", Role: group.Player}, nil)
+	smallBlind, bigBlind := 50, 100
+	tableRequest := TableRequest{ConfigChange: model.ConfigChange{
+		SmallBlind: &smallBlind, BigBlind: &bigBlind}}
+	if status := request(t, server, joe, http.MethodPost, path+"/table",
+		tableRequest, nil); status != http.StatusForbidden {
+		t.Error("expected a player not to create tables got", status)
+	}
+	for _, limits := range [][2]int{{-100, 0}, {2000, 1000}, {50, 1000}} {
+		invalid := tableRequest
+		invalid.MinBuyIn, invalid.MaxBuyIn = limits[0], limits[1]
+		if status := request(t, server, anna, http.MethodPost, path+"/table",
+			invalid, nil); status != http.StatusBadRequest {
+			t.Error("expected buy in limits", limits, "to fail got", status)
+		}
+	}
+	if status := request(t, server, joe, http.MethodPost, path,
+		ModifyGroupRequest{Member: "Joe", Credit: 1000},
+		nil); status != http.StatusForbidden {
+		t.Error("expected a player not to credit bankrolls got", status)
+	}
+	var table TableResponse
+	request(t, server, anna, http.MethodPost, path+"/table", tableRequest,
+		&table)
+	if table.ID == "" || table.Config.BigBlind != 100 ||
+		table.Config.TimeToBet != defaultTimeToBet {
+		t.Fatal("expected a table with a big blind of 100 got", table)
+	}
+	tablePath := path + "/table/" + table.ID
+	allow := true
+	modify := TableRequest{ConfigChange: model.ConfigChange{
+		AllowBlindModification: &allow}}
+	if status := request(t, server, joe, http.MethodPost, tablePath, modify,
+		nil); status != http.StatusForbidden {
+		t.Error("expected a player not to modify tables got", status)
+	}
+	var modified TableResponse
+	request(t, server, anna, http.MethodPost, tablePath, modify, &modified)
+	if !modified.Config.AllowBlindModification || modified.Playing {
+		t.Error("expected blind modification to be allowed got", modified)
+	}
+	smallBlind = 200
+	if status := request(t, server, anna, http.MethodPost, tablePath,
+		TableRequest{ConfigChange: model.ConfigChange{SmallBlind: &smallBlind}},
+		nil); status != http.StatusBadRequest {
+		t.Error("expected a small blind over the big blind to fail got", status)
+	}
+	var info GroupResponse
+	request(t, server, joe, http.MethodGet, path, nil, &info)
+	if len(info.Tables) != 1 || info.Tables[0] != table.ID {
+		t.Error("expected the group to own the table got", info.Tables)
+	}
+}
+
+func TestGroupServerPauseAndStop(t *testing.T) {
+	SetQuiet()
+	gs := NewGroupServer()
+	server := httptest.NewServer(gs)
+	defer server.Close()
+	anna, joe := newSessionToken("Anna"), newSessionToken("Joe")
+	var created GroupResponse
+	request(t, server, anna, http.MethodPost, "/group",
+		GroupRequest{Name: "Friday game"}, &created)
+	path := "/group/" + created.ID
+	request(t, server, anna, http.MethodPost, path,
+		ModifyGroupRequest{Member: "Joe", Role: group.Player}, nil)
+	for _, name := range []string{"Anna", "Joe"} {
+		if status := request(t, server, anna, http.MethodPost, path,
+			ModifyGroupRequest{Member: name, Credit: 1000},
+			nil); status != http.StatusOK {
+			t.Fatal("expected Anna to credit", name, "got", status)
+		}
+	}
+	timeToBet := 100 * time.Millisecond
+	var table TableResponse
+	request(t, server, anna, http.MethodPost, path+"/table",
+		TableRequest{ConfigChange: model.ConfigChange{TimeToBet: &timeToBet}},
+		&table)
+	tablePath := path + "/table/" + table.ID
+	annaConn := dialTable(t, server, anna, tablePath)
+	defer annaConn.Close()
+	joeConn := dialTable(t, server, joe, tablePath)
+	defer joeConn.Close()
+	send(t, annaConn, WebsocketRequest{
+		WebsocketRequestType: SitDownRequestT, BuyIn: 1000})
+	send(t, joeConn, WebsocketRequest{
+		WebsocketRequestType: SitDownRequestT, Seat: 1, BuyIn: 1000})
+	for state := readUntil(t, joeConn, StateResponseT).State; state.Seats[0] == nil ||
+		state.Seats[1] == nil; {
+		state = readUntil(t, joeConn, StateResponseT).State
+	}
+	send(t, joeConn, WebsocketRequest{WebsocketRequestType: StartGameRequestT})
+	readUntil(t, joeConn, HandResponseT)
+	send(t, joeConn, WebsocketRequest{WebsocketRequestType: PauseGameRequestT})
+	if response := readUntil(t, joeConn, ErrorResponseT); !strings.Contains(
+		response.Error, "admins") {
+		t.Error("expected only admins to pause got", response.Error)
+	}
+	send(t, annaConn, WebsocketRequest{WebsocketRequestType: PauseGameRequestT})
+	for state := readUntil(t, joeConn, StateResponseT).State; !state.Paused; {
+		state = readUntil(t, joeConn, StateResponseT).State
+	}
+	send(t, joeConn, WebsocketRequest{WebsocketRequestType: TurnRequestT, Fold: true})
+	if response := readUntil(t, joeConn, ErrorResponseT); !strings.Contains(
+		response.Error, "paused") {
+		t.Error("expected no turns while paused got", response.Error)
+	}
+	send(t, annaConn, WebsocketRequest{WebsocketRequestType: StartGameRequestT})
+	for state := readUntil(t, joeConn, StateResponseT).State; state.Paused; {
+		state = readUntil(t, joeConn, StateResponseT).State
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := gs.Tables.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if gs.Tables.Playing(created.ID, table.ID) {
+		t.Error("expected the table to have stopped")
+	}
+}
+
+func TestGroupServerChatModeration(t *testing.T) {
+	SetQuiet()
+	gs := NewGroupServer()
+	server := httptest.NewServer(gs)
+	defer server.Close()
+	anna, joe, bob := uuid.Must(uuid.NewV4()).String(),
+		uuid.Must(uuid.NewV4()).String(), uuid.Must(uuid.NewV4()).String()
+	sessionCache.Put(anna, model.NewPlayerWithFunds("Anna", 1000))
+	sessionCache.Put(joe, model.NewPlayerWithFunds("Joe", 1000))
+	sessionCache.Put(bob, model.NewPlayerWithFunds("Bob", 1000))
+	var created GroupResponse
+	request(t, server, anna, http.MethodPost, "/group",
+		GroupRequest{Name: "Friday game"}, &created)
+	path := "/group/" + created.ID
+	request(t, server, anna, http.MethodPost, path,
+		ModifyGroupRequest{Member: "Joe", Role: group.Player}, nil)
+	request(t, server, anna, http.MethodPost, path,
+		ModifyGroupRequest{Member: "Bob", Role: group.Admin}, nil)
+	var table TableResponse
+	request(t, server, anna, http.MethodPost, path+"/table", TableRequest{},
+		&table)
+	tablePath := path + "/table/" + table.ID
+	annaConn := dialTable(t, server, anna, tablePath)
+	defer annaConn.Close()
+	joeConn := dialTable(t, server, joe, tablePath)
+	defer joeConn.Close()
+	readUntil(t, joeConn, SnapshotResponseT)
+	bobConn := dialTable(t, server, bob, tablePath)
+	defer bobConn.Close()
+	for _, name := range []string{"Anna", "Bob"} {
+		send(t, bobConn, WebsocketRequest{
+			WebsocketRequestType: KickRequestT, PlayerName: name})
+		if response := readUntil(t, bobConn, ErrorResponseT); !strings.Contains(
+			response.Error, "moderate") {
+			t.Error("expected Bob not to kick", name, "got", response.Error)
+		}
+	}
+	send(t, annaConn, WebsocketRequest{
+		WebsocketRequestType: MuteRequestT, PlayerName: "Joe"})
+	if chat := readUntil(t, joeConn, ChatResponseT).Chat; !chat[0].Dealer ||
+		!strings.Contains(chat[0].Message, "Joe was muted") {
+		t.Error("expected the dealer to announce Joe was muted got", chat)
+	}
+	send(t, joeConn, WebsocketRequest{
+		WebsocketRequestType: ChatRequestT, Message: "hello"})
+	if response := readUntil(t, joeConn, ErrorResponseT); !strings.Contains(
+		response.Error, "muted") {
+		t.Error("expected Joe to be muted got", response.Error)
+	}
+	send(t, annaConn, WebsocketRequest{
+		WebsocketRequestType: KickRequestT, PlayerName: "Joe"})
+	if response := readUntil(t, joeConn, ErrorResponseT); !strings.Contains(
+		response.Error, "kicked") {
+		t.Error("expected Joe to be kicked got", response.Error)
+	}
+	joeConn = dialTable(t, server, joe, tablePath)
+	defer joeConn.Close()
+	if response := readUntil(t, joeConn, ErrorResponseT); !strings.Contains(
+		response.Error, "kicked") {
+		t.Error("expected Joe to be refused got", response.Error)
+	}
+}
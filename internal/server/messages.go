@@ -0,0 +1,146 @@
+package gateway
+
+import (
+	model "github.com/ekotlikoff/gopoker/internal/model/table"
+)
+
+// ProtocolVersion of the table websocket messages, requests of any other
+// version are refused
+const ProtocolVersion = 1
+
+const (
+	// SitDownRequestT sit at Seat, buying in for BuyIn if the table has a bank.
+	// Seat may be model.AnySeat or model.RandomSeat.
+	SitDownRequestT = WebsocketRequestType(iota)
+	// StandUpRequestT stand up from the table once the current hand is over
+	StandUpRequestT = WebsocketRequestType(iota)
+	// TurnRequestT fold, or bet a total of Bet for the round
+	TurnRequestT = WebsocketRequestType(iota)
+	// BlindModificationRequestT change the blinds from the next hand
+	BlindModificationRequestT = WebsocketRequestType(iota)
+	// StartGameRequestT start dealing hands
+	StartGameRequestT = WebsocketRequestType(iota)
+	// PauseGameRequestT stop dealing hands
+	PauseGameRequestT = WebsocketRequestType(iota)
+	// JoinWaitlistRequestT wait for a seat at the full table
+	JoinWaitlistRequestT = WebsocketRequestType(iota)
+	// LeaveWaitlistRequestT stop waiting for a seat
+	LeaveWaitlistRequestT = WebsocketRequestType(iota)
+	// AcceptSeatRequestT take the seat offered from the waitlist, buying in
+	// for BuyIn if the table has a bank
+	AcceptSeatRequestT = WebsocketRequestType(iota)
+	// DeclineSeatRequestT turn down the seat offered from the waitlist
+	DeclineSeatRequestT = WebsocketRequestType(iota)
+	// SeatChangeRequestT move to Seat once it is free
+	SeatChangeRequestT = WebsocketRequestType(iota)
+	// CancelSeatChangeRequestT withdraw a seat change request
+	CancelSeatChangeRequestT = WebsocketRequestType(iota)
+	// ChatRequestT send Message to the table's chat
+	ChatRequestT = WebsocketRequestType(iota)
+	// MuteRequestT stop the player named PlayerName chatting, admins only
+	MuteRequestT = WebsocketRequestType(iota)
+	// UnmuteRequestT let the muted player named PlayerName chat again,
+	// admins only
+	UnmuteRequestT = WebsocketRequestType(iota)
+	// KickRequestT disconnect the player named PlayerName and stand them up
+	// from the table, which refuses them for a while, admins only
+	KickRequestT = WebsocketRequestType(iota)
+)
+
+const (
+	// StateResponseT the table's state has changed
+	StateResponseT = WebsocketResponseType(iota)
+	// HandResponseT the player has been dealt a new hand
+	HandResponseT = WebsocketResponseType(iota)
+	// TurnResponseT a player has taken their turn
+	TurnResponseT = WebsocketResponseType(iota)
+	// ConfigChangeResponseT the table's config has changed
+	ConfigChangeResponseT = WebsocketResponseType(iota)
+	// ResultResponseT a hand has finished and its pots were awarded
+	ResultResponseT = WebsocketResponseType(iota)
+	// ErrorResponseT a request failed
+	ErrorResponseT = WebsocketResponseType(iota)
+	// SnapshotResponseT the table's full state and the player's hole cards,
+	// sent on connecting, with the events missed since the sequence number
+	// the client reconnected with
+	SnapshotResponseT = WebsocketResponseType(iota)
+	// ChatResponseT a message was sent to the table's chat
+	ChatResponseT = WebsocketResponseType(iota)
+)
+
+type (
+	// WebsocketRequestType the type of a client's WebsocketRequest
+	WebsocketRequestType int
+
+	// WebsocketResponseType the type of a server's WebsocketResponse
+	WebsocketResponseType int
+
+	// WebsocketRequest a message sent by the client over a table's websocket
+	WebsocketRequest struct {
+		Version              int
+		WebsocketRequestType WebsocketRequestType
+		// Seat and BuyIn for SitDownRequestT, BuyIn for AcceptSeatRequestT
+		// and Seat for SeatChangeRequestT
+		Seat  int
+		BuyIn int
+		// Fold or Bet for TurnRequestT
+		Fold bool
+		Bet  int
+		// SmallBlind, BigBlind and Ante for BlindModificationRequestT
+		SmallBlind int
+		BigBlind   int
+		Ante       int
+		// Message for ChatRequestT
+		Message string
+		// PlayerName for MuteRequestT, UnmuteRequestT and KickRequestT
+		PlayerName string
+	}
+
+	// WebsocketResponse a message sent by the server over a table's websocket.
+	// Turn, ConfigChange and Result responses are events numbered by
+	// Sequence, other responses carry the Sequence of the last event they
+	// reflect.
+	WebsocketResponse struct {
+		Version               int
+		WebsocketResponseType WebsocketResponseType
+		Sequence              int                `json:",omitempty"`
+		State                 *model.TableState  `json:",omitempty"`
+		Hole                  []string           `json:",omitempty"`
+		Turn                  *TurnResponse      `json:",omitempty"`
+		Config                *model.ConfigState `json:",omitempty"`
+		Winners               []WinnerResponse   `json:",omitempty"`
+		// Missed events of a Snapshot, in order
+		Missed []WebsocketResponse `json:",omitempty"`
+		// Chat the message of a Chat response, or the recent messages of a
+		// Snapshot, oldest first
+		Chat  []ChatMessage `json:",omitempty"`
+		Error string        `json:",omitempty"`
+	}
+
+	// TurnResponse the action a player took on their turn
+	TurnResponse struct {
+		PlayerName string
+		Fold       bool
+		// Bet the player's total bet for the round
+		Bet int
+	}
+
+	// WinnerResponse a player's share of a pot
+	WinnerResponse struct {
+		PlayerName  string
+		Amount      int
+		Description string
+	}
+)
+
+func newResponse(responseType WebsocketResponseType) WebsocketResponse {
+	return WebsocketResponse{
+		Version: ProtocolVersion, WebsocketResponseType: responseType,
+	}
+}
+
+func errorResponse(err error) WebsocketResponse {
+	response := newResponse(ErrorResponseT)
+	response.Error = err.Error()
+	return response
+}
@@ -0,0 +1,639 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ekotlikoff/gopoker/internal/model/group"
+	model "github.com/ekotlikoff/gopoker/internal/model/table"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// clientSendBuffer responses queued for a client before it is dropped as
+	// too slow
+	clientSendBuffer = 64
+	// turnTimeout how long a turn waits for the table to ask for the
+	// player's action
+	turnTimeout  = time.Second
+	writeTimeout = 10 * time.Second
+	// eventHistorySize events kept to replay to reconnecting clients
+	eventHistorySize = 256
+)
+
+var upgrader = websocket.Upgrader{}
+
+type (
+	// TableServer serves the websocket of each table played by a group
+	TableServer struct {
+		tables      map[tableKey]*tableHub
+		tablesMutex sync.RWMutex
+		// chatFilter checks every player's chat message, if set
+		chatFilter ChatFilter
+	}
+
+	tableKey struct {
+		group string
+		table string
+	}
+
+	// tableHub connects the clients of a table to the goroutine playing it
+	tableHub struct {
+		table   *model.Table
+		clients map[*tableClient]struct{}
+		playing bool
+		// stop playing the table, stopped is closed once it has
+		stop    context.CancelFunc
+		stopped chan struct{}
+		// config the clients were last sent
+		config model.ConfigState
+		// sequence of the last event sent to the clients, the most recent
+		// events are kept to replay to reconnecting clients
+		sequence int
+		events   []WebsocketResponse
+		chat     tableChat
+		// connections of each player, a player's connection state at the
+		// table is changed along with their count
+		connections map[*model.Player]int
+		hubMutex    sync.Mutex
+	}
+
+	// tableClient is a websocket connection bound to a session's player
+	tableClient struct {
+		conn   *websocket.Conn
+		player *model.Player
+		// group the client connected through, nil if it was served without
+		// one, and whether the player is an admin of it
+		group *group.Group
+		admin bool
+		send  chan WebsocketResponse
+		// hole the cards the client was last sent
+		hole []string
+	}
+)
+
+// NewTableServer create a server with no tables
+func NewTableServer() *TableServer {
+	return &TableServer{tables: make(map[tableKey]*tableHub)}
+}
+
+// AddTable serve table's websocket at /group/{groupID}/table/{tableID}
+func (ts *TableServer) AddTable(groupID string, tableID string,
+	table *model.Table) error {
+	ts.tablesMutex.Lock()
+	defer ts.tablesMutex.Unlock()
+	key := tableKey{group: groupID, table: tableID}
+	if _, ok := ts.tables[key]; ok {
+		return fmt.Errorf("addtable: table %s already exists in group %s",
+			tableID, groupID)
+	}
+	hub := &tableHub{
+		table: table, clients: make(map[*tableClient]struct{}),
+		config: table.Config(), chat: newTableChat(ts.chatFilter),
+		connections: make(map[*model.Player]int),
+	}
+	table.AddStateListener(hub.stateChanged)
+	table.AddHandListener(hub.handFinished)
+	table.AddWaitlistListener(hub.waitlistChanged)
+	ts.tables[key] = hub
+	return nil
+}
+
+func (ts *TableServer) hub(groupID string, tableID string) *tableHub {
+	ts.tablesMutex.RLock()
+	defer ts.tablesMutex.RUnlock()
+	return ts.tables[tableKey{group: groupID, table: tableID}]
+}
+
+// Playing if the table is being played
+func (ts *TableServer) Playing(groupID string, tableID string) bool {
+	hub := ts.hub(groupID, tableID)
+	if hub == nil {
+		return false
+	}
+	hub.hubMutex.Lock()
+	defer hub.hubMutex.Unlock()
+	return hub.playing
+}
+
+// Stop every table once its current hand is finished, waiting for them to
+// stop until ctx is done
+func (ts *TableServer) Stop(ctx context.Context) error {
+	ts.tablesMutex.RLock()
+	var stopped []chan struct{}
+	for _, hub := range ts.tables {
+		hub.hubMutex.Lock()
+		if hub.playing {
+			hub.stop()
+			stopped = append(stopped, hub.stopped)
+		}
+		hub.hubMutex.Unlock()
+	}
+	ts.tablesMutex.RUnlock()
+	for _, done := range stopped {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// refresh send the table's clients its state, e.g. after its config changed
+func (ts *TableServer) refresh(groupID string, tableID string) {
+	if hub := ts.hub(groupID, tableID); hub != nil {
+		hub.stateChanged(hub.table, nil)
+	}
+}
+
+// ServeHTTP upgrade GET /group/{id}/table/{id} to the table's websocket
+func (ts *TableServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(path) != 4 || path[0] != "group" || path[2] != "table" {
+		http.NotFound(w, r)
+		return
+	}
+	player := GetSession(w, r)
+	if player == nil {
+		return
+	}
+	ts.serve(w, r, player, path[1], path[3], nil)
+}
+
+// serve the table's websocket to player, through the table's group g if it
+// is not nil, whose admins can manage the table
+func (ts *TableServer) serve(w http.ResponseWriter, r *http.Request,
+	player *model.Player, groupID string, tableID string, g *group.Group) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	hub := ts.hub(groupID, tableID)
+	if hub == nil {
+		http.NotFound(w, r)
+		return
+	}
+	since := -1
+	if query := r.URL.Query().Get("since"); query != "" {
+		var err error
+		if since, err = strconv.Atoi(query); err != nil || since < 0 {
+			httpError(w, http.StatusBadRequest,
+				fmt.Errorf("invalid event sequence %q", query))
+			return
+		}
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Println("Failed to upgrade to websocket", err)
+		return
+	}
+	client, err := hub.connect(conn, player, g, since)
+	if err != nil {
+		log.Println(player.Name, "refused from table,", err)
+		return
+	}
+	defer hub.disconnect(client)
+	for {
+		var request WebsocketRequest
+		if err := conn.ReadJSON(&request); err != nil {
+			log.Println(player.Name, "disconnected from table,", err)
+			return
+		}
+		if err := hub.handle(client, request); err != nil {
+			hub.sendTo(client, errorResponse(err))
+		}
+	}
+}
+
+// connect the player to the table, a player that isn't seated watches it as
+// a stander. Once the table's standers are full the connection is refused.
+// The client is sent a snapshot of the table, with the events after since if
+// it is reconnecting. A player recently kicked from the table is refused.
+func (hub *tableHub) connect(conn *websocket.Conn, player *model.Player,
+	g *group.Group, since int) (*tableClient, error) {
+	client := &tableClient{
+		conn: conn, player: player, group: g,
+		send: make(chan WebsocketResponse, clientSendBuffer),
+	}
+	if g != nil {
+		role, _ := g.Role(player.Name)
+		client.admin = role.AtLeast(group.Admin)
+	}
+	go client.write()
+	hub.hubMutex.Lock()
+	defer hub.hubMutex.Unlock()
+	var err error
+	if hub.banned(player.Name) {
+		err = errors.New("you were kicked from the table")
+	} else if err = hub.table.Watch(player); !errors.Is(err,
+		model.ErrStandersFull) {
+		err = nil
+	}
+	if err != nil {
+		client.send <- errorResponse(err)
+		close(client.send)
+		return nil, err
+	}
+	hub.connections[player]++
+	hub.table.SetConnected(player, true)
+	hub.clients[client] = struct{}{}
+	hub.sendSnapshot(client, since)
+	return client, nil
+}
+
+// disconnect the client, a stander with no other connection to the table
+// stops watching it and leaves its waitlist. A seated player keeps their seat
+// and their clock keeps running, they can reconnect to act before it runs out
+// or the table's disconnect protection does. The player's connections are
+// counted, and their connection state changed, holding hubMutex so a player
+// reconnecting meanwhile is never marked disconnected.
+func (hub *tableHub) disconnect(client *tableClient) {
+	player := client.player
+	hub.hubMutex.Lock()
+	if _, ok := hub.clients[client]; ok {
+		delete(hub.clients, client)
+		close(client.send)
+	}
+	hub.connections[player]--
+	connected := hub.connections[player] > 0
+	if !connected {
+		delete(hub.connections, player)
+		hub.table.SetConnected(player, false)
+		if hub.table.Watching(player) {
+			hub.table.StopWatching(player)
+		}
+	}
+	hub.hubMutex.Unlock()
+	if !connected {
+		hub.stateChanged(hub.table, nil)
+	}
+}
+
+// write the client's responses to its connection until it disconnects
+func (client *tableClient) write() {
+	defer client.conn.Close()
+	for response := range client.send {
+		client.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if err := client.conn.WriteJSON(response); err != nil {
+			log.Println("Failed to write to", client.player.Name, err)
+			return
+		}
+	}
+}
+
+// handle a client's request, an error is sent back to the client
+func (hub *tableHub) handle(client *tableClient,
+	request WebsocketRequest) error {
+	if request.Version != ProtocolVersion {
+		return fmt.Errorf("unsupported protocol version %d, expected %d",
+			request.Version, ProtocolVersion)
+	}
+	table, player := hub.table, client.player
+	switch request.WebsocketRequestType {
+	case SitDownRequestT:
+		var err error
+		if table.Bank != nil {
+			err = table.BuyIn(player, request.Seat, request.BuyIn)
+		} else {
+			err = table.SitDown(player, request.Seat)
+		}
+		if err != nil {
+			return err
+		}
+		hub.stateChanged(table, nil)
+	case StandUpRequestT:
+		if err := table.StandUp(player); err != nil {
+			return err
+		}
+		hub.stateChanged(table, nil)
+	case TurnRequestT:
+		if table.Paused() {
+			return errors.New("turn: the game is paused")
+		}
+		return takeTurn(player, request)
+	case BlindModificationRequestT:
+		if !client.admin && !table.Config().AllowBlindModification {
+			return errors.New(
+				"blindmodification: only admins can change the blinds")
+		}
+		if err := table.ModifyBlinds(request.SmallBlind, request.BigBlind,
+			request.Ante); err != nil {
+			return err
+		}
+		hub.stateChanged(table, nil)
+	case StartGameRequestT:
+		if !table.Paused() {
+			return hub.start()
+		} else if !client.admin {
+			return errors.New("startgame: only admins can resume the game")
+		} else if err := table.Resume(); err != nil {
+			return err
+		}
+		hub.stateChanged(table, nil)
+	case PauseGameRequestT:
+		if !client.admin {
+			return errors.New("pausegame: only admins can pause the game")
+		} else if err := table.Pause(); err != nil {
+			return err
+		}
+		hub.stateChanged(table, nil)
+	case JoinWaitlistRequestT:
+		if err := table.JoinWaitlist(player); err != nil {
+			return err
+		}
+		hub.stateChanged(table, nil)
+	case LeaveWaitlistRequestT:
+		if err := table.LeaveWaitlist(player); err != nil {
+			return err
+		}
+		hub.stateChanged(table, nil)
+	case AcceptSeatRequestT:
+		if _, err := table.AcceptSeat(player, request.BuyIn); err != nil {
+			return err
+		}
+		hub.stateChanged(table, nil)
+	case DeclineSeatRequestT:
+		if err := table.DeclineSeat(player); err != nil {
+			return err
+		}
+		hub.stateChanged(table, nil)
+	case SeatChangeRequestT:
+		if err := table.RequestSeatChange(player, request.Seat); err != nil {
+			return err
+		}
+		hub.stateChanged(table, nil)
+	case CancelSeatChangeRequestT:
+		if err := table.CancelSeatChange(player); err != nil {
+			return err
+		}
+		hub.stateChanged(table, nil)
+	case ChatRequestT:
+		return hub.sendChat(client, request.Message)
+	case MuteRequestT:
+		return hub.mute(client, request.PlayerName, true)
+	case UnmuteRequestT:
+		return hub.mute(client, request.PlayerName, false)
+	case KickRequestT:
+		return hub.kick(client, request.PlayerName)
+	default:
+		return fmt.Errorf("unknown request type %d",
+			request.WebsocketRequestType)
+	}
+	return nil
+}
+
+// takeTurn pass the player's action to the table, which only listens for it
+// on the player's turn
+func takeTurn(player *model.Player, request WebsocketRequest) error {
+	action := model.NewRoundAction(model.Bet, request.Bet)
+	if request.Fold {
+		action = model.NewRoundAction(model.Fold, 0)
+	}
+	select {
+	case player.ActionChan <- action:
+		return nil
+	case <-time.After(turnTimeout):
+		return errors.New("turn: it's not your turn to bet")
+	}
+}
+
+// start playing the table in a new goroutine
+func (hub *tableHub) start() error {
+	hub.hubMutex.Lock()
+	defer hub.hubMutex.Unlock()
+	if hub.playing {
+		return errors.New("startgame: table is already playing")
+	}
+	hub.playing = true
+	ctx, stop := context.WithCancel(context.Background())
+	hub.stop, hub.stopped = stop, make(chan struct{})
+	go func() {
+		err := hub.table.PlayContext(ctx)
+		hub.hubMutex.Lock()
+		hub.playing = false
+		hub.stop()
+		close(hub.stopped)
+		hub.hubMutex.Unlock()
+		log.Println("Table stopped playing,", err)
+		hub.broadcast(errorResponse(err))
+	}()
+	return nil
+}
+
+// stateChanged send each client the table's config if it has changed, the
+// turn taken, if any, and their view of the table's new state
+func (hub *tableHub) stateChanged(table *model.Table, turn *model.Turn) {
+	hub.hubMutex.Lock()
+	defer hub.hubMutex.Unlock()
+	if config := table.Config(); config != hub.config {
+		hub.config = config
+		response := newResponse(ConfigChangeResponseT)
+		response.Config = &config
+		response = hub.record(response)
+		for client := range hub.clients {
+			hub.send(client, response)
+		}
+	}
+	var turnResponse *WebsocketResponse
+	if turn != nil {
+		response := newResponse(TurnResponseT)
+		response.Turn = &TurnResponse{
+			PlayerName: turn.Player.Name, Fold: turn.Fold, Bet: turn.Bet,
+		}
+		response = hub.record(response)
+		turnResponse = &response
+	}
+	for client := range hub.clients {
+		if turnResponse != nil {
+			hub.send(client, *turnResponse)
+		}
+		hub.sendState(client)
+	}
+}
+
+// waitlistChanged send every client the table's state after a seat offer
+// expired
+func (hub *tableHub) waitlistChanged(table *model.Table) {
+	hub.stateChanged(table, nil)
+}
+
+// handFinished send every client the winners of the hand's pots, which the
+// dealer also announces in the chat
+func (hub *tableHub) handFinished(table *model.Table,
+	result *model.HandResult) {
+	response := newResponse(ResultResponseT)
+	for _, pot := range result.Pots {
+		for _, winner := range pot.Winners {
+			response.Winners = append(response.Winners, WinnerResponse{
+				PlayerName: winner.Player.Name, Amount: winner.Amount,
+				Description: winner.Description,
+			})
+		}
+	}
+	hub.hubMutex.Lock()
+	defer hub.hubMutex.Unlock()
+	response = hub.record(response)
+	for client := range hub.clients {
+		hub.send(client, response)
+	}
+	for _, winner := range response.Winners {
+		if winner.Description == "" {
+			hub.dealerSays("%s wins %d", winner.PlayerName, winner.Amount)
+		} else {
+			hub.dealerSays("%s wins %d with %s", winner.PlayerName,
+				winner.Amount, winner.Description)
+		}
+	}
+}
+
+// record the next event sent to the clients, keeping it to replay to
+// reconnecting clients. Called holding hubMutex.
+func (hub *tableHub) record(response WebsocketResponse) WebsocketResponse {
+	hub.sequence++
+	response.Sequence = hub.sequence
+	hub.events = append(hub.events, response)
+	if len(hub.events) > eventHistorySize {
+		hub.events = hub.events[len(hub.events)-eventHistorySize:]
+	}
+	return response
+}
+
+// sendState send the client its view of the table, and its hole cards if
+// they were dealt since it was last sent them. Called holding hubMutex.
+func (hub *tableHub) sendState(client *tableClient) {
+	state, ok := hub.view(client)
+	if !ok {
+		return
+	}
+	hole := holeOf(state)
+	if len(hole) > 0 && !reflect.DeepEqual(hole, client.hole) {
+		response := newResponse(HandResponseT)
+		response.Hole = hole
+		response.Sequence = hub.sequence
+		hub.send(client, response)
+	}
+	client.hole = hole
+	response := newResponse(StateResponseT)
+	response.State = &state
+	response.Sequence = hub.sequence
+	hub.send(client, response)
+}
+
+// sendSnapshot send the client its view of the table, its hole cards and the
+// recent chat, with the events after since that are still kept if since is
+// not negative. Called holding hubMutex.
+func (hub *tableHub) sendSnapshot(client *tableClient, since int) {
+	state, ok := hub.view(client)
+	if !ok {
+		return
+	}
+	response := newResponse(SnapshotResponseT)
+	response.State = &state
+	response.Hole = holeOf(state)
+	response.Sequence = hub.sequence
+	response.Chat = hub.chatHistory()
+	for _, event := range hub.events {
+		if since >= 0 && event.Sequence > since {
+			response.Missed = append(response.Missed, event)
+		}
+	}
+	client.hole = response.Hole
+	hub.send(client, response)
+}
+
+// view the table as seen by the client, false if the client was dropped
+// because its player stood up while the table's standers were full. Called
+// holding hubMutex.
+func (hub *tableHub) view(client *tableClient) (model.TableState, bool) {
+	state := hub.table.State(client.player)
+	if !state.Present(client.player.Name) {
+		hub.send(client, errorResponse(model.ErrStandersFull))
+		hub.drop(client)
+		return state, false
+	}
+	return state, true
+}
+
+// holeOf the viewer's hole cards in state, nil if they have none
+func holeOf(state model.TableState) []string {
+	for _, seat := range state.Seats {
+		if seat != nil && seat.Hole != nil {
+			return seat.Hole
+		}
+	}
+	return nil
+}
+
+// currentMatch the table the player is seated at and their view of it, false
+// if they are not seated at a table served by ts
+func (ts *TableServer) currentMatch(player *model.Player) (CurrentMatch,
+	bool) {
+	table := player.GetTable()
+	if table == nil {
+		return CurrentMatch{}, false
+	}
+	ts.tablesMutex.RLock()
+	defer ts.tablesMutex.RUnlock()
+	for key, hub := range ts.tables {
+		if hub.table != table {
+			continue
+		}
+		hub.hubMutex.Lock()
+		defer hub.hubMutex.Unlock()
+		state := table.State(player)
+		return CurrentMatch{
+			GroupID: key.group, TableID: key.table, Sequence: hub.sequence,
+			State: &state,
+		}, true
+	}
+	return CurrentMatch{}, false
+}
+
+func (hub *tableHub) broadcast(response WebsocketResponse) {
+	hub.hubMutex.Lock()
+	defer hub.hubMutex.Unlock()
+	for client := range hub.clients {
+		hub.send(client, response)
+	}
+}
+
+func (hub *tableHub) sendTo(client *tableClient, response WebsocketResponse) {
+	hub.hubMutex.Lock()
+	defer hub.hubMutex.Unlock()
+	if _, ok := hub.clients[client]; ok {
+		hub.send(client, response)
+	}
+}
+
+// send queue a response for the client, unless it has been dropped. A client
+// that has fallen too far behind is dropped rather than holding up the table.
+// Called holding hubMutex.
+func (hub *tableHub) send(client *tableClient, response WebsocketResponse) {
+	if _, ok := hub.clients[client]; !ok {
+		return
+	}
+	select {
+	case client.send <- response:
+	default:
+		log.Println("Dropping slow client", client.player.Name)
+		hub.drop(client)
+	}
+}
+
+// drop the client's connection once its queued responses are written.
+// Called holding hubMutex.
+func (hub *tableHub) drop(client *tableClient) {
+	if _, ok := hub.clients[client]; ok {
+		delete(hub.clients, client)
+		close(client.send)
+	}
+}
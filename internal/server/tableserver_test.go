@@ -0,0 +1,351 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	model "github.com/ekotlikoff/gopoker/internal/model/table"
+	"github.com/gofrs/uuid"
+	"github.com/gorilla/websocket"
+)
+
+func dialTable(t *testing.T, server *httptest.Server, token string,
+	path string) *websocket.Conn {
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + path
+	header := http.Header{}
+	header.Add("Cookie", "session_token="+token)
+	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+// readUntil reads responses until one of responseType is found
+func readUntil(t *testing.T, conn *websocket.Conn,
+	responseType WebsocketResponseType) WebsocketResponse {
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var response WebsocketResponse
+		if err := conn.ReadJSON(&response); err != nil {
+			t.Fatal("expected response", responseType, err)
+		}
+		if response.WebsocketResponseType == responseType {
+			return response
+		}
+	}
+}
+
+func send(t *testing.T, conn *websocket.Conn, request WebsocketRequest) {
+	request.Version = ProtocolVersion
+	if err := conn.WriteJSON(request); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTableServer(t *testing.T) {
+	SetQuiet()
+	ts := NewTableServer()
+	table := model.NewTableWithConfig(
+		model.NewTableConfig(200, 5*time.Second, 0).WithBlindModification())
+	if err := ts.AddTable("g1", "t1", table); err != nil {
+		t.Fatal(err)
+	}
+	if err := ts.AddTable("g1", "t1", table); err == nil {
+		t.Error("expected a duplicate table to fail")
+	}
+	letoToken, paulToken := uuid.Must(uuid.NewV4()).String(),
+		uuid.Must(uuid.NewV4()).String()
+	sessionCache.Put(letoToken, model.NewPlayerWithFunds("Leto", 1000))
+	sessionCache.Put(paulToken, model.NewPlayerWithFunds("Paul", 1000))
+	server := httptest.NewServer(ts)
+	defer server.Close()
+	res, err := http.Get(server.URL + "/group/g1/table/t2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Error("expected a request without a session to be refused got",
+			res.StatusCode)
+	}
+	leto := dialTable(t, server, letoToken, "/group/g1/table/t1")
+	defer leto.Close()
+	paul := dialTable(t, server, paulToken, "/group/g1/table/t1")
+	defer paul.Close()
+
+	leto.WriteJSON(WebsocketRequest{Version: ProtocolVersion + 1})
+	if response := readUntil(t, leto, ErrorResponseT); !strings.Contains(
+		response.Error, "version") {
+		t.Error("expected a version error got", response.Error)
+	}
+	send(t, leto, WebsocketRequest{WebsocketRequestType: SitDownRequestT})
+	send(t, paul, WebsocketRequest{
+		WebsocketRequestType: SitDownRequestT, Seat: 1})
+	send(t, paul, WebsocketRequest{WebsocketRequestType: BlindModificationRequestT,
+		SmallBlind: 50, BigBlind: 100})
+	config := readUntil(t, leto, ConfigChangeResponseT).Config
+	if config == nil || config.BigBlind != 100 {
+		t.Error("expected the big blind to change to 100 got", config)
+	}
+	send(t, leto, WebsocketRequest{WebsocketRequestType: StartGameRequestT})
+	hand := readUntil(t, paul, HandResponseT)
+	if len(hand.Hole) != 2 {
+		t.Error("expected two hole cards got", hand.Hole)
+	}
+	// Heads up Paul posts the small blind and acts first
+	state := readUntil(t, leto, StateResponseT).State
+	for state.BetTurnSeat == -1 {
+		state = readUntil(t, leto, StateResponseT).State
+	}
+	if state.BetTurnSeat != 1 || state.Seats[1].Hole != nil {
+		t.Error("expected Paul to act without Leto seeing their hole got",
+			state.BetTurnSeat, state.Seats[1])
+	}
+	send(t, leto, WebsocketRequest{WebsocketRequestType: TurnRequestT, Fold: true})
+	readUntil(t, leto, ErrorResponseT)
+	send(t, paul, WebsocketRequest{WebsocketRequestType: TurnRequestT, Bet: 300})
+	turn := readUntil(t, leto, TurnResponseT).Turn
+	if turn.PlayerName != "Paul" || turn.Bet != 300 || turn.Fold {
+		t.Error("expected Paul to raise to 300 got", turn)
+	}
+	send(t, leto, WebsocketRequest{WebsocketRequestType: TurnRequestT, Fold: true})
+	result := readUntil(t, paul, ResultResponseT)
+	if len(result.Winners) != 1 || result.Winners[0].PlayerName != "Paul" ||
+		result.Winners[0].Amount != 200 {
+		t.Error("expected Paul to win the blinds got", result.Winners)
+	}
+}
+
+func TestTableServerWaitlist(t *testing.T) {
+	SetQuiet()
+	ts := NewTableServer()
+	table := model.NewTable()
+	if err := ts.AddTable("g1", "t1", table); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < model.MaxTableSize; i++ {
+		table.SitDown(model.NewPlayerWithFunds(fmt.Sprint("Player", i), 1000), i)
+	}
+	tokens := make(map[string]string)
+	for _, name := range []string{"Leto", "Paul", "Joe"} {
+		tokens[name] = uuid.Must(uuid.NewV4()).String()
+		sessionCache.Put(tokens[name], model.NewPlayerWithFunds(name, 1000))
+	}
+	server := httptest.NewServer(ts)
+	defer server.Close()
+	leto := dialTable(t, server, tokens["Leto"], "/group/g1/table/t1")
+	defer leto.Close()
+	send(t, leto, WebsocketRequest{WebsocketRequestType: SitDownRequestT})
+	state := readUntil(t, leto, StateResponseT).State
+	for state.Seats[0] == nil {
+		state = readUntil(t, leto, StateResponseT).State
+	}
+	paul := dialTable(t, server, tokens["Paul"], "/group/g1/table/t1")
+	defer paul.Close()
+	send(t, paul, WebsocketRequest{WebsocketRequestType: JoinWaitlistRequestT})
+	for len(state.Waitlist) == 0 {
+		state = readUntil(t, leto, StateResponseT).State
+	}
+	send(t, leto, WebsocketRequest{WebsocketRequestType: StandUpRequestT})
+	for len(state.SeatOffers) == 0 {
+		state = readUntil(t, paul, StateResponseT).State
+	}
+	if state.SeatOffers[0].Name != "Paul" || state.SeatOffers[0].Seat != 0 ||
+		len(state.Standers) != 2 {
+		t.Error("expected Leto's seat to be offered to Paul got", state)
+	}
+	send(t, paul, WebsocketRequest{WebsocketRequestType: AcceptSeatRequestT})
+	for state.Seats[0] == nil || state.Seats[0].Name != "Paul" {
+		state = readUntil(t, leto, StateResponseT).State
+	}
+	// Leto is still watching, fill the rest of the standers
+	for i := 1; i < model.MaxStandersSize; i++ {
+		if err := table.Watch(model.NewPlayer(fmt.Sprint("Stander", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	joe := dialTable(t, server, tokens["Joe"], "/group/g1/table/t1")
+	defer joe.Close()
+	if response := readUntil(t, joe, ErrorResponseT); !strings.Contains(
+		response.Error, "standers are full") {
+		t.Error("expected Joe to be refused got", response.Error)
+	}
+}
+
+func TestTableServerSeatChange(t *testing.T) {
+	SetQuiet()
+	ts := NewTableServer()
+	if err := ts.AddTable("g1", "t1", model.NewTable()); err != nil {
+		t.Fatal(err)
+	}
+	token := uuid.Must(uuid.NewV4()).String()
+	sessionCache.Put(token, model.NewPlayerWithFunds("Leto", 1000))
+	server := httptest.NewServer(ts)
+	defer server.Close()
+	leto := dialTable(t, server, token, "/group/g1/table/t1")
+	defer leto.Close()
+	send(t, leto, WebsocketRequest{
+		WebsocketRequestType: SitDownRequestT, Seat: model.AnySeat})
+	state := readUntil(t, leto, StateResponseT).State
+	for state.Seats[0] == nil {
+		state = readUntil(t, leto, StateResponseT).State
+	}
+	send(t, leto, WebsocketRequest{
+		WebsocketRequestType: SeatChangeRequestT, Seat: 3})
+	for state.Seats[3] == nil {
+		state = readUntil(t, leto, StateResponseT).State
+	}
+	if state.Seats[0] != nil || !state.Seats[3].WaitingForBigBlind {
+		t.Error("expected Leto to move and wait for the big blind got",
+			state.Seats)
+	}
+}
+
+func TestTableServerReconnect(t *testing.T) {
+	SetQuiet()
+	ts := NewTableServer()
+	table := model.NewTableWithConfig(
+		model.NewTableConfig(200, 5*time.Second, 0))
+	if err := ts.AddTable("g1", "t1", table); err != nil {
+		t.Fatal(err)
+	}
+	letoToken, paulToken := uuid.Must(uuid.NewV4()).String(),
+		uuid.Must(uuid.NewV4()).String()
+	sessionCache.Put(letoToken, model.NewPlayerWithFunds("Leto", 1000))
+	sessionCache.Put(paulToken, model.NewPlayerWithFunds("Paul", 1000))
+	server := httptest.NewServer(ts)
+	defer server.Close()
+	leto := dialTable(t, server, letoToken, "/group/g1/table/t1")
+	paul := dialTable(t, server, paulToken, "/group/g1/table/t1")
+	defer paul.Close()
+	send(t, leto, WebsocketRequest{WebsocketRequestType: SitDownRequestT})
+	send(t, paul, WebsocketRequest{
+		WebsocketRequestType: SitDownRequestT, Seat: 1})
+	state := readUntil(t, leto, StateResponseT).State
+	for state.Seats[1] == nil {
+		state = readUntil(t, leto, StateResponseT).State
+	}
+	send(t, leto, WebsocketRequest{WebsocketRequestType: StartGameRequestT})
+	response := readUntil(t, leto, StateResponseT)
+	for response.State.BetTurnSeat != 1 {
+		response = readUntil(t, leto, StateResponseT)
+	}
+	// Leto drops and misses Paul's raise, his clock keeps running
+	leto.Close()
+	state = readUntil(t, paul, StateResponseT).State
+	for state.Seats[0] == nil || !state.Seats[0].Disconnected {
+		state = readUntil(t, paul, StateResponseT).State
+	}
+	send(t, paul, WebsocketRequest{WebsocketRequestType: TurnRequestT, Bet: 400})
+	readUntil(t, paul, TurnResponseT)
+
+	gw := &Gateway{Groups: &GroupServer{Tables: ts}}
+	req := httptest.NewRequest(http.MethodGet, "/session", nil)
+	req.AddCookie(&http.Cookie{Name: "session_token", Value: letoToken})
+	recorder := httptest.NewRecorder()
+	gw.session(recorder, req)
+	var session SessionResponse
+	if err := json.NewDecoder(recorder.Body).Decode(&session); err != nil {
+		t.Fatal(err)
+	}
+	if !session.InMatch || session.Match.TableID != "t1" ||
+		session.Match.State == nil || session.Match.State.BetTurnSeat != 0 {
+		t.Fatal("expected Leto's session to be at table t1 got", session)
+	}
+
+	leto = dialTable(t, server, letoToken, fmt.Sprintf(
+		"/group/g1/table/t1?since=%d", response.Sequence))
+	defer leto.Close()
+	snapshot := readUntil(t, leto, SnapshotResponseT)
+	if len(snapshot.Missed) != 1 || snapshot.Missed[0].Turn == nil ||
+		snapshot.Missed[0].Turn.PlayerName != "Paul" ||
+		snapshot.Missed[0].Sequence != snapshot.Sequence {
+		t.Error("expected Leto to have missed Paul's raise got", snapshot.Missed)
+	}
+	if len(snapshot.Hole) != 2 || snapshot.State.BetTurnSeat != 0 ||
+		snapshot.State.Seats[0].Disconnected ||
+		snapshot.State.TimeToAct <= 0 ||
+		snapshot.State.TimeToAct > 5*time.Second {
+		t.Error("expected Leto to be on the clock got", snapshot.State)
+	}
+	send(t, leto, WebsocketRequest{WebsocketRequestType: TurnRequestT, Fold: true})
+	result := readUntil(t, leto, ResultResponseT)
+	if len(result.Winners) != 1 || result.Winners[0].PlayerName != "Paul" {
+		t.Error("expected Paul to win after Leto folds got", result.Winners)
+	}
+}
+
+func TestTableServerChat(t *testing.T) {
+	SetQuiet()
+	ts := NewTableServer()
+	if err := ts.AddTable("g1", "t1", model.NewTable()); err != nil {
+		t.Fatal(err)
+	}
+	ts.SetChatFilter(func(playerName string, message string) (string, error) {
+		if strings.Contains(message, "spam") {
+			return "", fmt.Errorf("%s's message was refused", playerName)
+		}
+		return strings.ReplaceAll(message, "darn", "****"), nil
+	})
+	letoToken := uuid.Must(uuid.NewV4()).String()
+	paulToken := uuid.Must(uuid.NewV4()).String()
+	sessionCache.Put(letoToken, model.NewPlayerWithFunds("Leto", 1000))
+	sessionCache.Put(paulToken, model.NewPlayerWithFunds("Paul", 1000))
+	server := httptest.NewServer(ts)
+	defer server.Close()
+	leto := dialTable(t, server, letoToken, "/group/g1/table/t1")
+	defer leto.Close()
+	paul := dialTable(t, server, paulToken, "/group/g1/table/t1")
+	defer paul.Close()
+	readUntil(t, paul, SnapshotResponseT)
+	send(t, leto, WebsocketRequest{
+		WebsocketRequestType: ChatRequestT, Message: " darn it "})
+	chat := readUntil(t, paul, ChatResponseT).Chat
+	if len(chat) != 1 || chat[0].PlayerName != "Leto" ||
+		chat[0].Message != "**** it" || chat[0].Dealer {
+		t.Error("expected Leto's filtered message got", chat)
+	}
+	send(t, leto, WebsocketRequest{
+		WebsocketRequestType: ChatRequestT, Message: "spam"})
+	if response := readUntil(t, leto, ErrorResponseT); !strings.Contains(
+		response.Error, "refused") {
+		t.Error("expected the filter to refuse the message got",
+			response.Error)
+	}
+	send(t, leto, WebsocketRequest{
+		WebsocketRequestType: ChatRequestT,
+		Message:              strings.Repeat("a", maxChatLength+1)})
+	if response := readUntil(t, leto, ErrorResponseT); !strings.Contains(
+		response.Error, "longer") {
+		t.Error("expected long messages to be refused got", response.Error)
+	}
+	for i := 1; i < chatBurst; i++ {
+		send(t, leto, WebsocketRequest{
+			WebsocketRequestType: ChatRequestT, Message: fmt.Sprint(i)})
+	}
+	send(t, leto, WebsocketRequest{
+		WebsocketRequestType: ChatRequestT, Message: "one too many"})
+	if response := readUntil(t, leto, ErrorResponseT); !strings.Contains(
+		response.Error, "too quickly") {
+		t.Error("expected Leto to be rate limited got", response.Error)
+	}
+	send(t, paul, WebsocketRequest{
+		WebsocketRequestType: MuteRequestT, PlayerName: "Leto"})
+	if response := readUntil(t, paul, ErrorResponseT); !strings.Contains(
+		response.Error, "admins") {
+		t.Error("expected only admins to mute got", response.Error)
+	}
+	paul.Close()
+	paul = dialTable(t, server, paulToken, "/group/g1/table/t1")
+	snapshot := readUntil(t, paul, SnapshotResponseT)
+	if len(snapshot.Chat) != chatBurst ||
+		snapshot.Chat[0].Message != "**** it" {
+		t.Error("expected the chat history in the snapshot got",
+			snapshot.Chat)
+	}
+}
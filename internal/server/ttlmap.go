@@ -49,16 +49,11 @@ func (m *TTLMap) Len() int {
 // Put puts key k and value v
 func (m *TTLMap) Put(k string, v *model.Player) error {
 	m.l.Lock()
-	_, ok := m.m[k]
-	var it item
-	if !ok {
-		it := &item{value: v}
-		m.m[k] = it
-	} else {
+	defer m.l.Unlock()
+	if _, ok := m.m[k]; ok {
 		return errors.New("failed to put key: " + k + ", value: " + v.Name)
 	}
-	it.lastAccess = time.Now().Unix()
-	m.l.Unlock()
+	m.m[k] = &item{value: v, lastAccess: time.Now().Unix()}
 	return nil
 }
 
@@ -0,0 +1,30 @@
+package gateway
+
+import (
+	"testing"
+
+	model "github.com/ekotlikoff/gopoker/internal/model/table"
+)
+
+func TestTTLMapPut(t *testing.T) {
+	m := NewTTLMap(1, 60, 60)
+	anna := model.NewPlayer("Anna")
+	if err := m.Put("token", anna); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Put("token", model.NewPlayer("Bob")); err == nil {
+		t.Error("expected putting an existing key to fail")
+	}
+	if m.Len() != 1 {
+		t.Error("expected one session got", m.Len())
+	}
+	m.l.Lock()
+	lastAccess := m.m["token"].lastAccess
+	m.l.Unlock()
+	if lastAccess == 0 {
+		t.Error("expected the time the session was put to be recorded")
+	}
+	if player, err := m.Get("token"); err != nil || player != anna {
+		t.Error("expected to get Anna back got", player, err)
+	}
+}